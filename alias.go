@@ -0,0 +1,103 @@
+package objectcommander
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// sharedValue is a value registered under several identities by
+// RegisterValueAs
+type sharedValue struct {
+	refs int // refs is the number of identities still registered
+}
+
+// RegisterValueAs registers the value under every identity, e.g. a
+// *sql.DB looked up as "db", "primary" and "default". The identities
+// share the instance, which is closed once, and only the first identity
+// is indexed by the type. The instance is kept until every identity is
+// unregistered. Nothing is registered if one of the identities already
+// exists.
+func (c *Container) RegisterValueAs(value interface{}, names ...Identity) error {
+	if value == nil {
+		return fmt.Errorf("the value of %v should not be nil", names)
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("at least one identity is required for the value of %T", value)
+	}
+
+	v := reflect.ValueOf(value)
+	ftype := reflect.FuncOf(nil, []reflect.Type{v.Type()}, false)
+	build := reflect.MakeFunc(ftype, func([]reflect.Value) []reflect.Value {
+		return []reflect.Value{v}
+	}).Interface()
+
+	c.Lock()
+	if c.frozen {
+		c.Unlock()
+		return FrozenContainerError{Op: "register", Name: names[0]}
+	}
+
+	for _, name := range names {
+		if _, exists := c.defs[name]; exists {
+			c.Unlock()
+			return AlreadyRegisteredError{
+				Name: name,
+				msg:  fmt.Sprintf("%s was already registered", name),
+			}
+		}
+	}
+
+	shared := &sharedValue{}
+	for _, name := range names {
+		if _, exists := c.defs[name]; exists {
+			continue // the identity is given twice
+		}
+
+		c.seq++
+		c.defs[name] = &definition{build: build, seq: c.seq, shared: shared}
+		c.put(name, value)
+		shared.refs++
+	}
+
+	retType := v.Type()
+	registered := c.typeToIdentity[retType]
+	c.typeToIdentity[retType] = append(c.typeToIdentity[retType], names[0])
+	c.resetParamCache()
+	c.Unlock()
+
+	if len(registered) > 0 {
+		c.logf("type %s registered as %s is already registered as %s", retType, names[0], registered[0])
+	}
+
+	return nil
+}
+
+// dropShared releases the identity from the value it shares. If the
+// identity indexed the type, another identity of the value takes over.
+// The caller holds the lock and the identity is already removed from
+// the definitions.
+func (c *Container) dropShared(name Identity, def *definition) {
+	if def.shared == nil {
+		return
+	}
+	def.shared.refs--
+
+	retType := def.outType()
+	indexed := false
+	for _, id := range c.typeToIdentity[retType] {
+		indexed = indexed || id == name
+	}
+	if !indexed || def.shared.refs == 0 {
+		return
+	}
+
+	// the definitions are checked by the registration order to be stable
+	var next Identity
+	for id, other := range c.defs {
+		if other.shared == def.shared && (next == "" || other.seq < c.defs[next].seq) {
+			next = id
+		}
+	}
+	c.typeToIdentity[retType] = append(pop(c.typeToIdentity[retType], name), next)
+}
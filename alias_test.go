@@ -0,0 +1,54 @@
+package objectcommander
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegisterValueAs(t *testing.T) {
+
+	var closed []string
+	db := &fakeConn{name: "db", closed: &closed}
+
+	c := NewContainer(WithAutoClose())
+	if err := c.RegisterValueAs(db, "db", "primary", "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []Identity{"db", "primary", "default"} {
+		if c.MustGet(name) != db {
+			t.Errorf("%s should resolve the shared instance", name)
+		}
+	}
+
+	if ids := c.typeToIdentity[reflect.TypeOf(db)]; !reflect.DeepEqual(ids, []Identity{"db"}) {
+		t.Errorf("the type should be indexed once: %v", ids)
+	}
+
+	c.Unregister(Identity("db"))
+	if ids := c.typeToIdentity[reflect.TypeOf(db)]; !reflect.DeepEqual(ids, []Identity{"primary"}) {
+		t.Errorf("the next identity should take over the type: %v", ids)
+	}
+
+	var byType *fakeConn
+	if err := c.Assign(&byType); err != nil || byType != db {
+		t.Errorf("the shared instance should be kept: %v", err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(closed, ",") != "db" {
+		t.Errorf("the shared instance should be closed once: %v", closed)
+	}
+
+	if err := c.RegisterValueAs(db, "replica", "primary"); err == nil {
+		t.Error("should reject an identity which already exists")
+	}
+
+	if _, err := c.Get(Identity("replica")); err == nil {
+		t.Error("nothing should be registered on a collision")
+	}
+}
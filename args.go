@@ -0,0 +1,174 @@
+package objectcommander
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// RegisterWithArgs registers a builder whose leading params are literal
+// args given by GetWithArgs instead of being resolved from the container,
+// e.g. a client built for a region. The other params are resolved as usual.
+func (c *Container) RegisterWithArgs(name Identity, build Builder, opts ...RegisterOption) error {
+	return c.Register(name, build, append(opts, func(d *definition) {
+		d.withArgs = true
+	})...)
+}
+
+// argKey is a comparable key of an argument tuple
+type argKey struct {
+	value interface{}
+	next  interface{}
+}
+
+// keyOf returns the key of args, it's false if one of args isn't comparable
+func keyOf(args []interface{}) (interface{}, bool) {
+	var key interface{} = argKey{}
+	for i := len(args) - 1; i >= 0; i-- {
+		if !hashable(reflect.ValueOf(args[i])) {
+			return nil, false
+		}
+		key = argKey{value: args[i], next: key}
+	}
+
+	return key, true
+}
+
+// hashable reports whether v can be a map key without panicking. A
+// comparable type isn't enough since an interface field may hold a slice.
+func hashable(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		return hashable(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !hashable(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !hashable(v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return v.Type().Comparable()
+}
+
+// GetWithArgs gets the instance built with args as the leading params of
+// the builder. The instances are cached per argument tuple, so the same
+// args return the same instance and different args return different ones.
+// An instance built with a non-comparable arg, e.g. a slice or a map, can't
+// be cached and it's built on every call. The cached instances are closed
+// and dropped by Close. The identity must be registered by
+// RegisterWithArgs.
+func (c *Container) GetWithArgs(name Identity, args ...interface{}) (interface{}, error) {
+	c.RLock()
+	def, exists := c.defs[name]
+	parent := c.parent
+	c.RUnlock()
+
+	if !exists && parent != nil {
+		return parent.GetWithArgs(name, args...)
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("%s was not registered", name)
+	}
+
+	if !def.withArgs {
+		return nil, fmt.Errorf("%s isn't built with arguments, use Get", name)
+	}
+
+	key, cacheable := keyOf(args)
+	if cacheable {
+		c.RLock()
+		obj, exists := c.memo[name][key]
+		c.RUnlock()
+
+		if exists {
+			return obj, nil
+		}
+	}
+
+	if err := c.provide(name, def); err != nil {
+		return nil, err
+	}
+
+	literals, err := literalArgs(name, reflect.TypeOf(def.build), args)
+	if err != nil {
+		return nil, err
+	}
+
+	res := newResolution(context.Background()).enter(name)
+	res.literals = literals
+
+	ret, err := c.create(res, name)
+	if err != nil {
+		return nil, err
+	}
+	obj := ret.Interface()
+
+	if !cacheable {
+		c.track(res)
+		return obj, nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	// keep the instance built by a concurrent call with the same args
+	if existing, exists := c.memo[name][key]; exists {
+		if res.cleanup != nil {
+			c.transients = append(c.transients, res.cleanup)
+		}
+		return existing, nil
+	}
+
+	if c.memo == nil {
+		c.memo = make(map[Identity]map[interface{}]interface{})
+	}
+	if c.memo[name] == nil {
+		c.memo[name] = make(map[interface{}]interface{})
+	}
+	c.memo[name][key] = obj
+	c.memoized = append(c.memoized, c.closingsOf(name, obj, res.cleanup)...)
+
+	return obj, nil
+}
+
+// literalArgs converts args to the leading params of the builder
+func literalArgs(name Identity, ftype reflect.Type, args []interface{}) ([]reflect.Value, error) {
+	numArgs := ftype.NumIn()
+	if ftype.IsVariadic() {
+		numArgs--
+	}
+
+	if len(args) > numArgs {
+		return nil, fmt.Errorf("builder of %s takes %d args but got %d", name, numArgs, len(args))
+	}
+
+	literals := make([]reflect.Value, 0, len(args))
+	for i, arg := range args {
+		argType := ftype.In(i)
+		if arg == nil {
+			literals = append(literals, reflect.Zero(argType))
+			continue
+		}
+
+		if t := reflect.TypeOf(arg); !t.AssignableTo(argType) {
+			return nil, fmt.Errorf("arg %d of %s should be %s but got %s", i, name, argType, t)
+		}
+		literals = append(literals, reflect.ValueOf(arg))
+	}
+
+	return literals, nil
+}
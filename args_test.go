@@ -0,0 +1,121 @@
+package objectcommander
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetWithArgs(t *testing.T) {
+
+	type Client struct {
+		Region string
+		Port   int
+		Name   string
+	}
+
+	c := NewContainer()
+	c.RegisterValue(Identity("name"), "app")
+	if err := c.RegisterWithArgs(Identity("client"), func(region string, port int, name string) *Client {
+		return &Client{Region: region, Port: port, Name: name}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	east, err := c.GetWithArgs(Identity("client"), "us-east", 443)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client := east.(*Client); client.Region != "us-east" || client.Port != 443 || client.Name != "app" {
+		t.Errorf("the client is built with the wrong args: %+v", client)
+	}
+
+	if again, _ := c.GetWithArgs(Identity("client"), "us-east", 443); again != east {
+		t.Error("the same args should return the same instance")
+	}
+
+	if west, _ := c.GetWithArgs(Identity("client"), "us-west", 443); west == east {
+		t.Error("different args should return different instances")
+	}
+
+	if _, err := c.GetWithArgs(Identity("client"), 443); err == nil {
+		t.Error("an arg of the wrong type should be rejected")
+	}
+
+	if _, err := c.Get(Identity("client")); err == nil {
+		t.Error("Get should be rejected for a builder taking args")
+	}
+}
+
+func TestGetWithArgsNotComparable(t *testing.T) {
+
+	c := NewContainer()
+	c.RegisterWithArgs(Identity("hosts"), func(hosts []string) *[]string {
+		return &hosts
+	})
+
+	args := []string{"a", "b"}
+	first, err := c.GetWithArgs(Identity("hosts"), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second, _ := c.GetWithArgs(Identity("hosts"), args); first == second {
+		t.Error("non-comparable args should always build")
+	}
+}
+
+func TestGetWithArgsNotComparableField(t *testing.T) {
+
+	type Options struct{ Value interface{} }
+
+	c := NewContainer()
+	c.RegisterWithArgs(Identity("options"), func(opts Options) *Options {
+		return &opts
+	})
+
+	opts := Options{Value: []string{"a", "b"}}
+	first, err := c.GetWithArgs(Identity("options"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second, _ := c.GetWithArgs(Identity("options"), opts); first == second {
+		t.Error("an arg holding a slice in an interface field should always build")
+	}
+}
+
+func TestGetWithArgsNotRegisteredWithArgs(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("port"), func() int { return 80 })
+
+	if _, err := c.GetWithArgs(Identity("port")); err == nil {
+		t.Error("should reject an identity which isn't registered with args")
+	}
+}
+
+func TestGetWithArgsClose(t *testing.T) {
+
+	type Client struct{ Host string }
+
+	var closed []string
+	c := NewContainer()
+	c.RegisterWithArgs(Identity("client"), func(host string) (*Client, func()) {
+		return &Client{Host: host}, func() { closed = append(closed, "cleanup "+host) }
+	}, WithCloser(func(v interface{}) error {
+		closed = append(closed, "close "+v.(*Client).Host)
+		return nil
+	}))
+
+	first, _ := c.GetWithArgs(Identity("client"), "localhost")
+	c.Close()
+
+	if strings.Join(closed, ",") != "close localhost,cleanup localhost" {
+		t.Errorf("the cached instance should be closed: %v", closed)
+	}
+
+	if again, _ := c.GetWithArgs(Identity("client"), "localhost"); again == first {
+		t.Error("the closed instance should not be returned again")
+	}
+}
@@ -0,0 +1,79 @@
+package objectcommander
+
+import (
+	"reflect"
+)
+
+// BindField assigns the instance of the identity to the target, which
+// should be a pointer, and assigns it again whenever the instance is
+// rebuilt, e.g. after the config is invalidated. It's meant for the long
+// lived objects holding a hot reloadable setting. The target is assigned
+// while the container is locked, so reading it concurrently with a rebuild
+// needs its own synchronization. An instance which isn't assignable to the
+// target is skipped, see Unbind to stop the assignments.
+func (c *Container) BindField(target interface{}, id Identity) error {
+	value, err := settable(target)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.Get(id)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	// the instance may be rebuilt since Get, the binding is recorded along
+	// with the current one so the rebuild isn't missed
+	if obj, exists := c.store[id]; exists {
+		result = c.defs[id].instance(obj)
+	}
+
+	if err := set(id, value, result); err != nil {
+		return err
+	}
+
+	if c.bindings == nil {
+		c.bindings = make(map[Identity][]reflect.Value)
+	}
+	c.bindings[id] = append(c.bindings[id], value)
+
+	return nil
+}
+
+// Unbind stops assigning the instance of the identity to the target bound
+// by BindField. The target keeps the instance it holds.
+func (c *Container) Unbind(target interface{}, id Identity) error {
+	value, err := settable(target)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	targets := c.bindings[id][:0]
+	for _, bound := range c.bindings[id] {
+		if bound.Type() != value.Type() || bound.Addr().Pointer() != value.Addr().Pointer() {
+			targets = append(targets, bound)
+		}
+	}
+
+	if len(targets) == 0 {
+		delete(c.bindings, id)
+	} else {
+		c.bindings[id] = targets
+	}
+
+	return nil
+}
+
+// rebind assigns the instance to the targets bound to the identity, the
+// caller holds the lock
+func (c *Container) rebind(name Identity, obj interface{}) {
+	for _, target := range c.bindings[name] {
+		set(name, target, obj)
+	}
+}
@@ -0,0 +1,91 @@
+package objectcommander
+
+import (
+	"testing"
+)
+
+func TestBindField(t *testing.T) {
+
+	type Config struct{ Version int }
+	type Handler struct{ Config *Config }
+
+	version := 0
+	c := NewContainer()
+	c.Register(Identity("config"), func() *Config {
+		version++
+		return &Config{Version: version}
+	})
+
+	handler := &Handler{}
+	if err := c.BindField(&handler.Config, Identity("config")); err != nil {
+		t.Fatal(err)
+	}
+
+	if handler.Config.Version != 1 {
+		t.Fatalf("the field should be assigned: %+v", handler.Config)
+	}
+
+	c.Invalidate(Identity("config"))
+	c.MustGet(Identity("config"))
+
+	if handler.Config.Version != 2 {
+		t.Errorf("the field should be assigned again once rebuilt: %+v", handler.Config)
+	}
+
+	var port int
+	if err := c.BindField(&port, Identity("config")); err == nil {
+		t.Error("should reject a target of another type")
+	}
+}
+
+func TestBindFieldWith(t *testing.T) {
+
+	type Config struct{ Version int }
+
+	c := NewContainer()
+	c.Register(Identity("config"), func() *Config { return &Config{Version: 1} })
+
+	var config *Config
+	c.BindField(&config, Identity("config"))
+
+	c.With(Identity("config"), &Config{Version: 2}, func() {
+		if config.Version != 2 {
+			t.Errorf("the field should be assigned the replacement: %+v", config)
+		}
+	})
+
+	if config.Version != 1 {
+		t.Errorf("the field should be assigned the previous instance again: %+v", config)
+	}
+}
+
+func TestUnbind(t *testing.T) {
+
+	type Config struct{ Version int }
+
+	version := 0
+	c := NewContainer()
+	c.Register(Identity("config"), func() *Config {
+		version++
+		return &Config{Version: version}
+	})
+
+	var bound, unbound *Config
+	c.BindField(&bound, Identity("config"))
+	c.BindField(&unbound, Identity("config"))
+
+	if err := c.Unbind(&unbound, Identity("config")); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Invalidate(Identity("config"))
+	c.MustGet(Identity("config"))
+
+	if bound.Version != 2 {
+		t.Errorf("the other target should still be bound: %+v", bound)
+	}
+
+	if unbound.Version != 1 {
+		t.Errorf("the unbound target should keep its instance: %+v", unbound)
+	}
+}
@@ -1,16 +1,112 @@
 package objectcommander
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
+// Phase describes which stage of a manager's lifecycle a step hook reports
+type Phase int
+
+const (
+	// PhaseStart is reported around the registration of a manager in Boot
+	PhaseStart Phase = iota
+	// PhaseClose is reported around the Close of a manager in Release
+	PhaseClose
+	// PhaseSkip is reported when a manager is skipped because its identity was already registered
+	PhaseSkip
+	// PhaseReady is reported around the Ready of a manager in Boot
+	PhaseReady
+	// PhasePreClose is reported around the PreClose of a manager in Release
+	PhasePreClose
+	// PhaseDrain is reported around the Drain of a manager in Release
+	PhaseDrain
+)
+
+// String returns the name of the phase
+func (p Phase) String() string {
+	switch p {
+	case PhaseStart:
+		return "start"
+	case PhaseClose:
+		return "close"
+	case PhaseSkip:
+		return "skip"
+	case PhaseReady:
+		return "ready"
+	case PhasePreClose:
+		return "pre-close"
+	case PhaseDrain:
+		return "drain"
+	}
+
+	return fmt.Sprintf("phase(%d)", int(p))
+}
+
+// StepHook is called before and after each step of Boot and Release. err is
+// always nil before a step and holds the step's result after it.
+type StepHook func(id Identity, phase Phase, err error)
+
 // Manager handles the resource's initialization and release
 type Manager struct {
-	ID    Identity
-	Start interface{}              // Start is a function responsible for initialization ex. init db instance
-	Close func(c *Container) error // Close is a function responsible for releasing resources.
+	ID        Identity
+	Start     interface{}              // Start is a function responsible for initialization ex. init db instance
+	Close     func(c *Container) error // Close is a function responsible for releasing resources.
+	DependsOn []Identity               // DependsOn are the managers to be started first besides the params of Start
+	Optional  bool                     // Optional makes Boot log the failure of the manager and go on instead of panicking
+	Ready     interface{}              // Ready is invoked with its params resolved once every manager is started ex. register with the service discovery
+	PreClose  func(c *Container) error // PreClose is called before any manager is closed ex. deregister from the service discovery
+
+	// Drain is invoked after every PreClose and before any manager is
+	// closed, to let the in-flight work finish ex. shut down an HTTP server
+	// gracefully. Its params are resolved from the container and a
+	// context.Context param gets a context which is done after the
+	// DrainTimeout. Release stops waiting for Drain once DrainTimeout
+	// passes, zero means no limit. It's skipped if the instance of the
+	// manager was never built.
+	Drain        interface{}
+	DrainTimeout time.Duration
+}
+
+// NewManager creates a manager from typed start and close functions, so
+// a close which doesn't take what start returns fails to compile:
+//
+//	NewManager(Identity("db"), openDB, func(db *sql.DB) error { return db.Close() })
+//
+// close may be nil if there is nothing to release, and it's only called if
+// the instance was built. It panics if start is nil.
+func NewManager[T any](id Identity, start func() T, close func(T) error) Manager {
+	if start == nil {
+		panic(fmt.Sprintf("the start of manager %s should not be nil", id))
+	}
+
+	m := Manager{ID: id, Start: start}
+	if close == nil {
+		return m
+	}
+
+	m.Close = func(c *Container) error {
+		// an instance which was never built has nothing to release
+		instance, built := c.Peek(id)
+		if !built {
+			return nil
+		}
+
+		typed, ok := instance.(T)
+		if !ok {
+			return fmt.Errorf("the instance of manager %s is a %T instead of %s", id, instance, typeOf[T]())
+		}
+
+		return close(typed)
+	}
+
+	return m
 }
 
 // NewBootstrap creates a bootstrap instance
@@ -25,31 +121,108 @@ func NewBootstrap(c *Container) *Bootstrap {
 	}
 }
 
+// NewBootstrapChild creates a bootstrap for a sub-system, e.g. a plugin,
+// whose container is a child of the parent's container. Its managers can
+// depend on the services of the parent, and releasing it only closes its
+// own managers and instances.
+func NewBootstrapChild(parent *Bootstrap) *Bootstrap {
+	return NewBootstrap(parent.GetContainer().Child())
+}
+
 // Bootstrap describes a series of procedures to be executed
 // before running the main function
 type Bootstrap struct {
 	container             *Container
 	successful_procedures []Manager
+	stepHooks             []StepHook
+	concurrentRelease     bool
+	eagerStart            bool
+	timings               map[Identity]time.Duration
+	readied               map[Identity]bool // readied are the managers of a lazy Boot whose Ready was invoked
 	sync.RWMutex
 }
 
+// OnStep adds a hook which is called before and after each manager is
+// started in Boot and closed in Release. It's useful for reporting
+// the progress of a slow startup.
+func (b *Bootstrap) OnStep(hook StepHook) *Bootstrap {
+	b.Lock()
+	defer b.Unlock()
+
+	b.stepHooks = append(b.stepHooks, hook)
+	return b
+}
+
+// notify calls the step hooks outside the bootstrap lock
+func (b *Bootstrap) notify(id Identity, phase Phase, err error) {
+	b.RLock()
+	hooks := append([]StepHook(nil), b.stepHooks...)
+	b.RUnlock()
+
+	for _, hook := range hooks {
+		hook(id, phase, err)
+	}
+}
+
 func (b *Bootstrap) GetContainer() *Container {
 	return b.container
 }
 
+// SetContainer replaces the container of the bootstrap, e.g. with one
+// holding fakes for a test. The managers whose identities are registered
+// in the container are skipped by Boot, so the fakes are kept. It fails
+// once a manager is booted.
+func (b *Bootstrap) SetContainer(c *Container) error {
+	if c == nil {
+		return errors.New("the container should not be nil")
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if len(b.successful_procedures) > 0 {
+		return fmt.Errorf("the container can't be replaced after %d managers are booted", len(b.successful_procedures))
+	}
+	b.container = c
+
+	return nil
+}
+
 // Release releases the resources which collected by the procedures
 func (b *Bootstrap) Release() error {
 	errorContent := ""
 
+	b.RLock()
+	concurrent := b.concurrentRelease
+	b.RUnlock()
+
 	for _, p := range b.successful_procedures {
-		if err := p.Close(b.container); err != nil {
-			errorContent += fmt.Sprintf("an error happens when closing a manager %s: %s", p.ID, err.Error())
+		errorContent += b.preCloseManager(p)
+	}
+
+	for _, p := range b.successful_procedures {
+		errorContent += b.drainManager(p)
+	}
+
+	if concurrent {
+		errorContent += b.releaseConcurrently()
+	} else {
+		for _, p := range b.successful_procedures {
+			errorContent += b.closeManager(p)
 		}
 	}
 
+	if err := b.container.Close(); err != nil {
+		errorContent += err.Error()
+	}
+
 	b.container.FlushALL()
 	b.successful_procedures = []Manager{}
 
+	b.Lock()
+	b.readied = nil
+	b.Unlock()
+
 	if errorContent != "" {
 		return errors.New(errorContent)
 	}
@@ -57,29 +230,586 @@ func (b *Bootstrap) Release() error {
 	return nil
 }
 
-// Boot executes the series of procedures
+// preCloseManager calls the PreClose of the manager and returns the error
+// message if it fails. Like Drain, it's skipped if the instance of the
+// manager was never built.
+func (b *Bootstrap) preCloseManager(p Manager) string {
+	if p.PreClose == nil {
+		return ""
+	}
+
+	if _, built := b.container.Peek(p.ID); !built {
+		return ""
+	}
+
+	b.notify(p.ID, PhasePreClose, nil)
+	err := p.PreClose(b.container)
+	b.notify(p.ID, PhasePreClose, err)
+
+	if err != nil {
+		return fmt.Sprintf("an error happens when pre-closing a manager %s: %s", p.ID, err.Error())
+	}
+
+	return ""
+}
+
+// contextType is the reflect type of context.Context
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// drainManager invokes the Drain of the manager within its DrainTimeout
+// and returns the error message if it fails
+func (b *Bootstrap) drainManager(p Manager) string {
+	if p.Drain == nil {
+		return ""
+	}
+
+	if _, built := b.container.Peek(p.ID); !built {
+		return ""
+	}
+
+	ctx, cancel := context.Background(), func() {}
+	if p.DrainTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, p.DrainTimeout)
+	}
+	defer cancel()
+
+	b.notify(p.ID, PhaseDrain, nil)
+
+	// a Drain which ignores the context keeps running in its own goroutine
+	done := make(chan error, 1)
+	go func() {
+		done <- b.container.InvokeOverride(p.Drain, map[reflect.Type]interface{}{contextType: ctx})
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("draining timed out after %s", p.DrainTimeout)
+	}
+	b.notify(p.ID, PhaseDrain, err)
+
+	if err != nil {
+		return fmt.Sprintf("an error happens when draining a manager %s: %s", p.ID, err.Error())
+	}
+
+	return ""
+}
+
+// closeManager closes the manager and returns the error message if it fails
+func (b *Bootstrap) closeManager(p Manager) string {
+	if p.Close == nil {
+		return ""
+	}
+
+	b.notify(p.ID, PhaseClose, nil)
+	err := p.Close(b.container)
+	b.notify(p.ID, PhaseClose, err)
+
+	if err != nil {
+		return fmt.Sprintf("an error happens when closing a manager %s: %s", p.ID, err.Error())
+	}
+
+	return ""
+}
+
+// Restart closes the manager, drops its instance and starts it again, so
+// one subsystem can recover without a full reboot. The instances built
+// from the old instance are kept unless the container is created
+// WithReactiveInvalidation. The manager isn't started again if it fails
+// to close.
+func (b *Bootstrap) Restart(id Identity) error {
+	b.RLock()
+	var manager *Manager
+	for i := range b.successful_procedures {
+		if b.successful_procedures[i].ID == id {
+			manager = &b.successful_procedures[i]
+			break
+		}
+	}
+	b.RUnlock()
+
+	if manager == nil {
+		return fmt.Errorf("%s is not a manager of the bootstrap", id)
+	}
+	p := *manager
+
+	if p.Close != nil {
+		b.notify(p.ID, PhaseClose, nil)
+		err := p.Close(b.container)
+		b.notify(p.ID, PhaseClose, err)
+
+		if err != nil {
+			return fmt.Errorf("failed to close %s: %w", p.ID, err)
+		}
+	}
+
+	if err := b.container.Invalidate(p.ID); err != nil {
+		return err
+	}
+
+	b.notify(p.ID, PhaseStart, nil)
+	err := b.start(p)
+	b.notify(p.ID, PhaseStart, err)
+
+	return err
+}
+
+// WithConcurrentRelease makes Release close the managers which don't
+// depend on each other concurrently. A manager is still closed before the
+// managers it depends on. The step hooks may be called concurrently.
+func (b *Bootstrap) WithConcurrentRelease() *Bootstrap {
+	b.Lock()
+	defer b.Unlock()
+
+	b.concurrentRelease = true
+	return b
+}
+
+// releaseConcurrently closes the managers level by level, starting from
+// the managers which nothing depends on
+func (b *Bootstrap) releaseConcurrently() string {
+	levels := b.levels(b.successful_procedures)
+	errorContent := ""
+
+	for i := len(levels) - 1; i >= 0; i-- {
+		var wg sync.WaitGroup
+		messages := make([]string, len(levels[i]))
+
+		for j, p := range levels[i] {
+			wg.Add(1)
+			go func(j int, p Manager) {
+				defer wg.Done()
+				messages[j] = b.closeManager(p)
+			}(j, p)
+		}
+		wg.Wait()
+
+		errorContent += strings.Join(messages, "")
+	}
+
+	return errorContent
+}
+
+// levels groups the managers by the depth of their dependencies among the
+// managers. The managers at level 0 don't depend on any other manager.
+func (b *Bootstrap) levels(procedures []Manager) [][]Manager {
+	managed := make(map[Identity]Manager, len(procedures))
+	for _, p := range procedures {
+		managed[p.ID] = p
+	}
+
+	depth := make(map[Identity]int, len(procedures))
+	var measure func(id Identity, visiting map[Identity]bool) int
+	measure = func(id Identity, visiting map[Identity]bool) int {
+		if d, exists := depth[id]; exists {
+			return d
+		}
+
+		// a cycle can't be ordered, treat it as independent
+		if visiting[id] {
+			return 0
+		}
+		visiting[id] = true
+
+		d := 0
+		for _, dep := range b.dependencies(managed[id]) {
+			if _, exists := managed[dep]; exists && dep != id {
+				if dd := measure(dep, visiting) + 1; dd > d {
+					d = dd
+				}
+			}
+		}
+
+		depth[id] = d
+		return d
+	}
+
+	levels := [][]Manager{}
+	for _, p := range procedures {
+		d := measure(p.ID, map[Identity]bool{})
+		for len(levels) <= d {
+			levels = append(levels, []Manager{})
+		}
+		levels[d] = append(levels[d], p)
+	}
+
+	return levels
+}
+
+// dependencies returns the identities the manager depends on, both the
+// params of Start and DependsOn
+func (b *Bootstrap) dependencies(p Manager) []Identity {
+	return append(b.container.Dependencies(p.ID), p.DependsOn...)
+}
+
+// sorted orders the managers so every manager comes after the managers it
+// depends on, the build order and then the order of the procedures are
+// kept otherwise, see BuildOrder. It returns an error if the managers
+// depend on each other or on an identity which isn't registered.
+func (b *Bootstrap) sorted(procedures []Manager) ([]Manager, error) {
+	// the build order breaks the ties among the independent managers
+	procedures = append([]Manager(nil), procedures...)
+	sort.SliceStable(procedures, func(i, j int) bool {
+		return b.container.buildOrderOf(procedures[i].ID) < b.container.buildOrderOf(procedures[j].ID)
+	})
+
+	managers := make(map[Identity]Manager, len(procedures))
+	for _, p := range procedures {
+		managers[p.ID] = p
+	}
+
+	sorted := make([]Manager, 0, len(procedures))
+	done := make(map[Identity]bool, len(procedures))
+
+	var visit func(id Identity, path []Identity) error
+	visit = func(id Identity, path []Identity) error {
+		if done[id] {
+			return nil
+		}
+
+		for i, visiting := range path {
+			if visiting == id {
+				ids := make([]string, 0, len(path)-i+1)
+				for _, p := range path[i:] {
+					ids = append(ids, string(p))
+				}
+				ids = append(ids, string(id))
+				return fmt.Errorf("dependency cycle detected among managers: %s", strings.Join(ids, " -> "))
+			}
+		}
+		path = append(path, id)
+
+		for _, dep := range b.dependencies(managers[id]) {
+			if _, managed := managers[dep]; managed {
+				if err := visit(dep, path); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if !b.container.has(dep) {
+				return fmt.Errorf("%s depends on %s which is not registered", id, dep)
+			}
+		}
+
+		done[id] = true
+		sorted = append(sorted, managers[id])
+		return nil
+	}
+
+	for _, p := range procedures {
+		if err := visit(p.ID, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
+
+// Boot executes the series of procedures. The managers are registered
+// lazily unless the bootstrap is created WithEagerStart, in which case
+// each manager is built once every procedure is registered. The managers
+// are built after the managers they depend on whatever the order of the
+// procedures is, and Boot panics if they depend on each other. A manager
+// which fails makes Boot close the managers it has built and panic, unless
+// the manager is Optional, in which case the failure is logged and the
+// manager is left out of the release. WithEagerStart, the Ready of the
+// managers are invoked once every manager is started, otherwise the Ready
+// of a manager is invoked once it's first built, and a failed Ready is
+// logged since the build which triggered it has already succeeded.
 func (b *Bootstrap) Boot(procedures []Manager) *Bootstrap {
+	b.RLock()
+	eager := b.eagerStart
+	b.RUnlock()
 
+	registered := []Manager{}
 	for _, p := range procedures {
-		err := b.container.Register(p.ID, p.Start)
+		if !eager {
+			b.notify(p.ID, PhaseStart, nil)
+		}
+
+		opts := []RegisterOption{}
+		if !eager && p.Ready != nil {
+			p := p
+			opts = append(opts, func(d *definition) {
+				d.built = func() { b.readyLazy(p) }
+			})
+		}
+		err := b.container.Register(p.ID, p.Start, opts...)
 
 		if err == nil {
+			if !eager {
+				b.notify(p.ID, PhaseStart, nil)
+			}
 			b.successful_procedures = append(b.successful_procedures, p)
+			registered = append(registered, p)
 			continue
 		}
 
 		if _, ok := err.(AlreadyRegisteredError); ok {
+			b.notify(p.ID, PhaseSkip, err)
+			continue
+		} else if p.Optional {
+			b.notify(p.ID, PhaseStart, err)
+			b.container.logf("optional manager %s failed to register: %s", p.ID, err)
 			continue
 		} else {
-			b.Release()
+			b.notify(p.ID, PhaseStart, err)
+			b.rollback()
 			panic(err)
 		}
 
 	}
 
+	if !eager {
+		return b
+	}
+
+	registered, err := b.sorted(registered)
+	if err != nil {
+		b.rollback()
+		panic(err)
+	}
+
+	for _, p := range registered {
+		b.notify(p.ID, PhaseStart, nil)
+		err := b.start(p)
+		b.notify(p.ID, PhaseStart, err)
+
+		if err != nil && p.Optional {
+			b.container.logf("optional manager %s failed to start: %s", p.ID, err)
+			b.drop(p.ID)
+			continue
+		}
+
+		if err != nil {
+			b.rollback()
+			panic(err)
+		}
+	}
+
+	if _, err := b.ready(registered); err != nil {
+		b.rollback()
+		panic(err)
+	}
+
+	return b
+}
+
+// ready invokes the Ready of the managers which are still booted. The
+// failed Ready of an Optional manager is logged, otherwise it stops and
+// the failed manager is returned with its error.
+func (b *Bootstrap) ready(procedures []Manager) (Identity, error) {
+	booted := make(map[Identity]bool, len(b.successful_procedures))
+	for _, p := range b.successful_procedures {
+		booted[p.ID] = true
+	}
+
+	for _, p := range procedures {
+		if p.Ready == nil || !booted[p.ID] {
+			continue
+		}
+
+		b.notify(p.ID, PhaseReady, nil)
+		err := b.container.Invoke(p.Ready)
+		b.notify(p.ID, PhaseReady, err)
+
+		if err != nil && p.Optional {
+			b.container.logf("optional manager %s failed to be ready: %s", p.ID, err)
+			continue
+		}
+
+		if err != nil {
+			return p.ID, err
+		}
+	}
+
+	return "", nil
+}
+
+// readyLazy invokes the Ready of a manager of a lazy Boot once it's first
+// built
+func (b *Bootstrap) readyLazy(p Manager) {
+	b.Lock()
+	if b.readied[p.ID] {
+		b.Unlock()
+		return
+	}
+	if b.readied == nil {
+		b.readied = make(map[Identity]bool)
+	}
+	b.readied[p.ID] = true
+	b.Unlock()
+
+	if _, err := b.ready([]Manager{p}); err != nil {
+		b.container.logf("manager %s failed to be ready: %s", p.ID, err)
+	}
+}
+
+// rollback releases what a failed Boot has built. Only the managers whose
+// instances were built are closed, so the Close of a manager which was
+// registered but never built doesn't build it to release it.
+func (b *Bootstrap) rollback() {
+	built := []Manager{}
+	for _, p := range b.successful_procedures {
+		if _, exists := b.container.Peek(p.ID); exists {
+			built = append(built, p)
+		}
+	}
+	b.successful_procedures = built
+
+	b.Release()
+}
+
+// drop removes the manager from the successful procedures so it isn't
+// closed by Release
+func (b *Bootstrap) drop(id Identity) {
+	for i, p := range b.successful_procedures {
+		if p.ID == id {
+			b.successful_procedures = append(b.successful_procedures[:i:i], b.successful_procedures[i+1:]...)
+			return
+		}
+	}
+}
+
+// WithEagerStart makes Boot build every manager it registers instead of
+// leaving them to be built on the first use
+func (b *Bootstrap) WithEagerStart() *Bootstrap {
+	b.Lock()
+	defer b.Unlock()
+
+	b.eagerStart = true
 	return b
 }
 
+// start builds the instance of the manager and records how long it takes
+func (b *Bootstrap) start(p Manager) error {
+	started := time.Now()
+	_, err := b.container.build(p.ID)
+	elapsed := time.Since(started)
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.timings == nil {
+		b.timings = make(map[Identity]time.Duration)
+	}
+	b.timings[p.ID] = elapsed
+
+	return err
+}
+
+// Warm builds the identities in order, e.g. to open a connection pool
+// before accepting traffic, without making every manager eager. The
+// managers they depend on are built first. It stops at the first identity
+// which fails to build and returns its error.
+func (b *Bootstrap) Warm(ids ...Identity) error {
+	b.RLock()
+	registered := make(map[Identity]Manager, len(b.successful_procedures))
+	for _, p := range b.successful_procedures {
+		registered[p.ID] = p
+	}
+	b.RUnlock()
+
+	// the managers which the warmed ones depend on are warmed first
+	warming := []Manager{}
+	seen := map[Identity]bool{}
+	var collect func(id Identity)
+	collect = func(id Identity) {
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+
+		p, exists := registered[id]
+		if !exists {
+			p = Manager{ID: id}
+		}
+		for _, dep := range p.DependsOn {
+			collect(dep)
+		}
+		warming = append(warming, p)
+	}
+	for _, id := range ids {
+		collect(id)
+	}
+
+	warming, err := b.sorted(warming)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range warming {
+		if err := b.start(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WarmAll builds every definition of the container, not only the managers,
+// and returns the errors of the ones which fail to build. Unlike Warm, it
+// doesn't stop at the first failure so it works as a startup self-test
+// which reports every broken builder at once, a builder which panics is
+// reported as well. The definitions taking args
+// from GetWithArgs can't be built without them and are skipped.
+func (b *Bootstrap) WarmAll() map[Identity]error {
+	errs := map[Identity]error{}
+	for _, id := range b.container.buildable() {
+		if err := b.tryStart(Manager{ID: id}); err != nil {
+			errs[id] = err
+		}
+	}
+
+	return errs
+}
+
+// tryStart works like start but returns the panic of the builder as an error
+func (b *Bootstrap) tryStart(p Manager) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("building %s panicked: %v", p.ID, r)
+		}
+	}()
+
+	return b.start(p)
+}
+
+// Timings returns how long it took to build each manager. Only the
+// managers built by Boot, see WithEagerStart, and by Warm or WarmAll are
+// recorded.
+func (b *Bootstrap) Timings() map[Identity]time.Duration {
+	b.RLock()
+	defer b.RUnlock()
+
+	timings := make(map[Identity]time.Duration, len(b.timings))
+	for id, elapsed := range b.timings {
+		timings[id] = elapsed
+	}
+
+	return timings
+}
+
+// MustBoot boots the procedures and returns the container. Like Boot, it
+// panics if a procedure fails. The caller is responsible for calling
+// Release once the resources are no longer needed.
+func (b *Bootstrap) MustBoot(procedures []Manager) *Container {
+	return b.Boot(procedures).container
+}
+
+// BootFrom boots the managers contributed by the providers. Each provider
+// gets the container so it can decide which managers to contribute.
+func (b *Bootstrap) BootFrom(providers ...func(c *Container) []Manager) *Bootstrap {
+	procedures := []Manager{}
+	for _, provide := range providers {
+		procedures = append(procedures, provide(b.container)...)
+	}
+
+	return b.Boot(procedures)
+}
+
 // Run performs the specify function after Booting the procedures
 // In addition, this will release the resources after executing the function
 func (b *Bootstrap) Run(f func()) {
@@ -1,9 +1,13 @@
 package objectcommander
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // a global variable for testing usage
@@ -84,3 +88,830 @@ func TestBoot(t *testing.T) {
 		t.Error("resources were not released")
 	}
 }
+
+func TestOnStep(t *testing.T) {
+
+	var steps []string
+	record := func(id Identity, phase Phase, err error) {
+		steps = append(steps, fmt.Sprintf("%s:%s:%v", id, phase, err != nil))
+	}
+
+	c := NewContainer()
+	c.Register(Identity("cache"), func() string { return "fake cache" })
+
+	noop := func(c *Container) error { return nil }
+	procedures := []Manager{
+		{ID: Identity("config"), Start: func() string { return "config" }, Close: noop},
+		{ID: Identity("cache"), Start: func() string { return "cache" }, Close: noop},
+	}
+
+	b := NewBootstrap(c).OnStep(record)
+	b.Boot(procedures)
+	b.Release()
+
+	expected := []string{
+		"config:start:false",
+		"config:start:false",
+		"cache:start:false",
+		"cache:skip:true",
+		"config:close:false",
+		"config:close:false",
+	}
+
+	if strings.Join(steps, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected steps: %v", steps)
+	}
+}
+
+func TestBootFrom(t *testing.T) {
+
+	var closed []string
+	manager := func(id string) Manager {
+		return Manager{
+			ID:    Identity(id),
+			Start: func() string { return id },
+			Close: func(c *Container) error {
+				closed = append(closed, id)
+				return nil
+			},
+		}
+	}
+
+	storage := func(c *Container) []Manager {
+		return []Manager{manager("db"), manager("cache")}
+	}
+	messaging := func(c *Container) []Manager {
+		return []Manager{manager("queue")}
+	}
+
+	b := NewBootstrap(nil).BootFrom(storage, messaging)
+
+	for _, id := range []string{"db", "cache", "queue"} {
+		if b.GetContainer().MustGet(Identity(id)).(string) != id {
+			t.Errorf("%s was not booted", id)
+		}
+	}
+
+	b.Release()
+	if strings.Join(closed, ",") != "db,cache,queue" {
+		t.Errorf("every manager should be released: %v", closed)
+	}
+}
+
+func TestConcurrentRelease(t *testing.T) {
+
+	type Queue struct{}
+	type Cache struct{}
+	type Worker struct{}
+
+	var mu sync.Mutex
+	var closed []string
+	record := func(id string) {
+		mu.Lock()
+		defer mu.Unlock()
+		closed = append(closed, id)
+	}
+
+	// queue and cache are independent so each waits for the other one to
+	// start closing, which can only succeed if they run concurrently.
+	queueClosing := make(chan struct{})
+	cacheClosing := make(chan struct{})
+	waitFor := func(self, other chan struct{}) error {
+		close(self)
+		select {
+		case <-other:
+			return nil
+		case <-time.After(time.Second):
+			return errors.New("independent managers were not closed concurrently")
+		}
+	}
+
+	procedures := []Manager{
+		{
+			ID:    Identity("queue"),
+			Start: func() Queue { return Queue{} },
+			Close: func(c *Container) error {
+				record("queue")
+				return waitFor(queueClosing, cacheClosing)
+			},
+		},
+		{
+			ID:    Identity("cache"),
+			Start: func() Cache { return Cache{} },
+			Close: func(c *Container) error {
+				record("cache")
+				return waitFor(cacheClosing, queueClosing)
+			},
+		},
+		{
+			ID:    Identity("worker"),
+			Start: func(q Queue) Worker { return Worker{} },
+			Close: func(c *Container) error {
+				record("worker")
+				return nil
+			},
+		},
+	}
+
+	b := NewBootstrap(nil).WithConcurrentRelease().Boot(procedures)
+	if err := b.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(closed) != 3 || closed[0] != "worker" {
+		t.Errorf("the worker should be closed before the queue it depends on: %v", closed)
+	}
+}
+
+func TestReleaseClosesContainer(t *testing.T) {
+
+	closed := false
+	c := NewContainer()
+	c.RegisterCloser(Identity("pool"), func() string { return "pool" }, func(interface{}) error {
+		closed = true
+		return nil
+	})
+
+	b := NewBootstrap(c).Boot([]Manager{
+		{ID: Identity("api"), Start: func(pool string) int { return 1 }},
+	})
+	b.GetContainer().MustGet(Identity("api"))
+
+	if err := b.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !closed {
+		t.Error("release should close the instances of the container")
+	}
+}
+
+func TestNewManager(t *testing.T) {
+
+	type Pool struct {
+		Open bool
+	}
+
+	pool := &Pool{}
+	manager := NewManager(Identity("pool"), func() *Pool {
+		pool.Open = true
+		return pool
+	}, func(p *Pool) error {
+		p.Open = false
+		return nil
+	})
+
+	b := NewBootstrap(nil).Boot([]Manager{manager})
+	if !b.GetContainer().MustGet(Identity("pool")).(*Pool).Open {
+		t.Error("the pool should be started")
+	}
+
+	if err := b.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	if pool.Open {
+		t.Error("the pool should be closed")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("a nil start should panic")
+		}
+	}()
+	NewManager[*Pool](Identity("nop"), nil, nil)
+}
+
+func TestMustBoot(t *testing.T) {
+
+	b := NewBootstrap(nil)
+	c := b.MustBoot([]Manager{
+		NewManager(Identity("db"), func() string { return "db" }, nil),
+	})
+	defer b.Release()
+
+	if c != b.GetContainer() || c.MustGet(Identity("db")).(string) != "db" {
+		t.Error("should return the booted container")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("should panic on an invalid procedure")
+		}
+	}()
+	NewBootstrap(nil).MustBoot([]Manager{{ID: Identity("invalid"), Start: "invalid"}})
+}
+
+func TestTimings(t *testing.T) {
+
+	built := []string{}
+	b := NewBootstrap(nil).WithEagerStart()
+	b.Boot([]Manager{
+		NewManager(Identity("config"), func() string {
+			built = append(built, "config")
+			return "config"
+		}, nil),
+		NewManager(Identity("db"), func() int {
+			built = append(built, "db")
+			time.Sleep(10 * time.Millisecond)
+			return 1
+		}, nil),
+	})
+	defer b.Release()
+
+	if strings.Join(built, ",") != "config,db" {
+		t.Errorf("the managers should be built during boot: %v", built)
+	}
+
+	timings := b.Timings()
+	if len(timings) != 2 {
+		t.Fatalf("expected a timing per manager: %v", timings)
+	}
+
+	for id, elapsed := range timings {
+		if elapsed < 0 {
+			t.Errorf("the timing of %s should not be negative", id)
+		}
+	}
+
+	if timings[Identity("db")] < 10*time.Millisecond {
+		t.Error("the timing should cover the build")
+	}
+}
+
+func TestEagerStartFailure(t *testing.T) {
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("a failed eager start should panic")
+		}
+	}()
+
+	NewBootstrap(nil).WithEagerStart().Boot([]Manager{
+		{ID: Identity("db"), Start: func() (string, error) { return "", errors.New("refused") }},
+	})
+}
+
+func TestOptionalManager(t *testing.T) {
+
+	logger := &recordLogger{}
+	c := NewContainer()
+	c.SetLogger(logger)
+
+	metricsClosed := false
+	b := NewBootstrap(c).WithEagerStart().Boot([]Manager{
+		{ID: Identity("db"), Start: func() string { return "db" }},
+		{
+			ID:       Identity("metrics"),
+			Start:    func() (int, error) { return 0, errors.New("refused") },
+			Close:    func(c *Container) error { metricsClosed = true; return nil },
+			Optional: true,
+		},
+	})
+
+	if len(b.successful_procedures) != 1 || b.successful_procedures[0].ID != Identity("db") {
+		t.Errorf("the failed optional manager should be excluded: %v", b.successful_procedures)
+	}
+
+	if len(logger.messages) != 1 {
+		t.Errorf("the failure should be logged: %v", logger.messages)
+	}
+
+	b.Release()
+	if metricsClosed {
+		t.Error("the failed optional manager should not be closed")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("a failed required manager should still panic")
+		}
+	}()
+
+	NewBootstrap(nil).WithEagerStart().Boot([]Manager{
+		{ID: Identity("metrics"), Start: func() (int, error) { return 0, errors.New("refused") }, Optional: true},
+		{ID: Identity("db"), Start: func() (string, error) { return "", errors.New("refused") }},
+	})
+}
+
+func TestReadyAndPreClose(t *testing.T) {
+
+	var steps []string
+	record := func(step string) func(c *Container) error {
+		return func(c *Container) error {
+			steps = append(steps, step)
+			return nil
+		}
+	}
+
+	type Listener struct{ Addr string }
+
+	b := NewBootstrap(nil).WithEagerStart().Boot([]Manager{
+		{
+			ID: Identity("listener"),
+			Start: func() *Listener {
+				steps = append(steps, "start listener")
+				return &Listener{Addr: ":80"}
+			},
+			Ready: func(l *Listener) {
+				steps = append(steps, "ready "+l.Addr)
+			},
+			PreClose: record("pre-close listener"),
+			Close:    record("close listener"),
+		},
+		{
+			ID: Identity("db"),
+			Start: func() string {
+				steps = append(steps, "start db")
+				return "db"
+			},
+			PreClose: record("pre-close db"),
+			Close:    record("close db"),
+		},
+	})
+	b.Release()
+
+	expected := []string{
+		"start listener",
+		"start db",
+		"ready :80",
+		"pre-close listener",
+		"pre-close db",
+		"close listener",
+		"close db",
+	}
+
+	if strings.Join(steps, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected steps: %v", steps)
+	}
+}
+
+func TestReadyLazy(t *testing.T) {
+
+	type Listener struct{ Addr string }
+
+	var steps []string
+	b := NewBootstrap(nil).Boot([]Manager{
+		{
+			ID: Identity("listener"),
+			Start: func() *Listener {
+				steps = append(steps, "start listener")
+				return &Listener{Addr: ":80"}
+			},
+			Ready: func(l *Listener) {
+				steps = append(steps, "ready "+l.Addr)
+			},
+			PreClose: func(c *Container) error {
+				steps = append(steps, "pre-close listener")
+				return nil
+			},
+		},
+		{
+			ID:    Identity("db"),
+			Start: func() string { return "db" },
+			PreClose: func(c *Container) error {
+				steps = append(steps, "pre-close db")
+				return nil
+			},
+		},
+	})
+
+	if len(steps) != 0 {
+		t.Fatalf("a lazy Boot should not start the manager for its Ready: %v", steps)
+	}
+
+	b.GetContainer().MustGet(Identity("listener"))
+	b.GetContainer().MustGet(Identity("listener"))
+	b.Release()
+
+	expected := "start listener,ready :80,pre-close listener"
+	if strings.Join(steps, ",") != expected {
+		t.Errorf("the Ready should be invoked once the manager is built and only the built managers pre-closed: %v", steps)
+	}
+}
+
+func TestDrain(t *testing.T) {
+
+	var steps []string
+	var mu sync.Mutex
+	record := func(step string) {
+		mu.Lock()
+		defer mu.Unlock()
+		steps = append(steps, step)
+	}
+
+	type Server struct{ Addr string }
+	type Worker struct{ Queue string }
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+
+	b := NewBootstrap(nil).WithEagerStart().Boot([]Manager{
+		{
+			ID:    Identity("server"),
+			Start: func() *Server { return &Server{Addr: ":80"} },
+			Drain: func(ctx context.Context, s *Server) error {
+				if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+					t.Error("the context should be done after the drain timeout")
+				}
+				record("drain " + s.Addr)
+				return nil
+			},
+			DrainTimeout: time.Second,
+			Close:        func(c *Container) error { record("close server"); return nil },
+		},
+		{
+			ID:    Identity("worker"),
+			Start: func() *Worker { return &Worker{Queue: "jobs"} },
+			Drain: func(w *Worker) {
+				record("drain " + w.Queue)
+				<-stuck
+			},
+			DrainTimeout: 10 * time.Millisecond,
+			Close:        func(c *Container) error { record("close worker"); return nil },
+		},
+	})
+
+	err := b.Release()
+	if err == nil || !strings.Contains(err.Error(), "draining a manager worker") {
+		t.Errorf("the drain exceeding its timeout should be reported: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	expected := []string{"drain :80", "drain jobs", "close server", "close worker"}
+	if strings.Join(steps, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected steps: %v", steps)
+	}
+}
+
+func TestSetContainer(t *testing.T) {
+
+	fakes := NewContainer()
+	fakes.RegisterValue(Identity("db"), "fake db")
+
+	b := NewBootstrap(nil)
+	if err := b.SetContainer(fakes); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Boot([]Manager{
+		{ID: Identity("db"), Start: func() string { return "db" }},
+		{ID: Identity("cache"), Start: func() int { return 6379 }},
+	})
+	defer b.Release()
+
+	if b.GetContainer() != fakes || fakes.MustGet(Identity("db")).(string) != "fake db" {
+		t.Error("the pre-registered fake should be kept")
+	}
+
+	if fakes.MustGet(Identity("cache")).(int) != 6379 {
+		t.Error("the other managers should be booted in the container")
+	}
+
+	if err := b.SetContainer(NewContainer()); err == nil {
+		t.Error("the container should not be replaced once booted")
+	}
+}
+
+func TestEagerStartFailureClosesBuiltManagers(t *testing.T) {
+
+	closed := []string{}
+	closer := func(id string) func(c *Container) error {
+		return func(c *Container) error {
+			closed = append(closed, id)
+			return nil
+		}
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("a failed eager start should panic")
+			}
+		}()
+
+		NewBootstrap(nil).WithEagerStart().Boot([]Manager{
+			{ID: Identity("config"), Start: func() string { return "config" }, Close: closer("config")},
+			{ID: Identity("db"), Start: func() int { return 5432 }, Close: closer("db")},
+			{ID: Identity("cache"), Start: func() (bool, error) { return false, errors.New("refused") }, Close: closer("cache")},
+			{ID: Identity("queue"), Start: func() float64 { return 1 }, Close: closer("queue")},
+		})
+	}()
+
+	if strings.Join(closed, ",") != "config,db" {
+		t.Errorf("only the managers which were built should be closed: %v", closed)
+	}
+}
+
+func TestConcurrentReleaseReportsPreClose(t *testing.T) {
+
+	closed := false
+	b := NewBootstrap(nil).WithConcurrentRelease().WithEagerStart().Boot([]Manager{
+		{
+			ID:       Identity("listener"),
+			Start:    func() string { return "listener" },
+			PreClose: func(c *Container) error { return errors.New("deregister failed") },
+			Close:    func(c *Container) error { closed = true; return nil },
+		},
+	})
+
+	err := b.Release()
+	if err == nil || !strings.Contains(err.Error(), "deregister failed") {
+		t.Errorf("the failed PreClose should be reported: %v", err)
+	}
+
+	if !closed {
+		t.Error("the manager should still be closed")
+	}
+}
+
+func TestWarm(t *testing.T) {
+
+	built := []string{}
+	b := NewBootstrap(nil)
+	b.Boot([]Manager{
+		NewManager(Identity("pool"), func() string {
+			built = append(built, "pool")
+			return "pool"
+		}, nil),
+		NewManager(Identity("cache"), func() int {
+			built = append(built, "cache")
+			return 1
+		}, nil),
+		{ID: Identity("broken"), Start: func() (bool, error) { return false, errors.New("unreachable") }},
+	})
+	defer b.Release()
+
+	if len(built) != 0 {
+		t.Fatalf("the managers should be built lazily: %v", built)
+	}
+
+	if err := b.Warm(Identity("pool")); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(built, ",") != "pool" {
+		t.Errorf("only the warmed identities should be built: %v", built)
+	}
+
+	if _, exists := b.Timings()[Identity("pool")]; !exists {
+		t.Error("the warm up should be timed")
+	}
+
+	if err := b.Warm(Identity("broken"), Identity("cache")); err == nil || !strings.Contains(err.Error(), "unreachable") {
+		t.Errorf("should surface the build error: %v", err)
+	}
+
+	if strings.Join(built, ",") != "pool" {
+		t.Errorf("the warm up should stop at the failure: %v", built)
+	}
+}
+
+func TestEagerStartOrder(t *testing.T) {
+
+	steps := []string{}
+	b := NewBootstrap(nil).WithEagerStart().OnStep(func(id Identity, phase Phase, err error) {
+		steps = append(steps, string(id))
+	})
+	b.Boot([]Manager{
+		{ID: Identity("server"), Start: func(db int) bool { return true }},
+		{ID: Identity("db"), Start: func(config string) int { return 1 }},
+		NewManager(Identity("config"), func() string { return "config" }, nil),
+	})
+	defer b.Release()
+
+	if strings.Join(steps, ",") != "config,config,db,db,server,server" {
+		t.Errorf("the managers should be started after their dependencies: %v", steps)
+	}
+}
+
+func TestEagerStartCycle(t *testing.T) {
+
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(fmt.Sprint(r), "a -> b -> a") {
+			t.Errorf("should panic with the cycle: %v", r)
+		}
+	}()
+
+	b := NewBootstrap(nil).WithEagerStart()
+	b.Boot([]Manager{
+		{ID: Identity("a"), Start: func(int) string { return "a" }},
+		{ID: Identity("b"), Start: func(string) int { return 1 }},
+	})
+}
+
+func TestManagerDependsOn(t *testing.T) {
+
+	started := []string{}
+	worker := NewManager(Identity("worker"), func() bool {
+		started = append(started, "worker")
+		return true
+	}, nil)
+	worker.DependsOn = []Identity{"db"}
+
+	db := NewManager(Identity("db"), func() int {
+		started = append(started, "db")
+		return 1
+	}, nil)
+
+	b := NewBootstrap(nil).WithEagerStart()
+	b.Boot([]Manager{worker, db})
+	b.Release()
+
+	if strings.Join(started, ",") != "db,worker" {
+		t.Errorf("the worker should be started after db: %v", started)
+	}
+
+	started = nil
+	lazy := NewBootstrap(nil)
+	lazy.Boot([]Manager{worker, db})
+	defer lazy.Release()
+
+	if err := lazy.Warm(Identity("worker")); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(started, ",") != "db,worker" {
+		t.Errorf("warming the worker should warm db first: %v", started)
+	}
+
+	worker.DependsOn = []Identity{"queue"}
+	if err := NewBootstrap(nil).Boot([]Manager{worker}).Warm(Identity("worker")); err == nil {
+		t.Error("should reject a dependency which isn't registered")
+	}
+}
+
+func TestRestart(t *testing.T) {
+
+	type Pool struct{ Generation int }
+
+	generation := 0
+	closed := []int{}
+	b := NewBootstrap(nil)
+	b.Boot([]Manager{
+		NewManager(Identity("pool"), func() *Pool {
+			generation++
+			return &Pool{Generation: generation}
+		}, func(p *Pool) error {
+			closed = append(closed, p.Generation)
+			return nil
+		}),
+		{ID: Identity("broken"), Start: func() int { return 1 }, Close: func(*Container) error {
+			return errors.New("stuck")
+		}},
+	})
+	defer b.Release()
+
+	old := b.GetContainer().MustGet(Identity("pool")).(*Pool)
+
+	if err := b.Restart(Identity("pool")); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh := b.GetContainer().MustGet(Identity("pool")).(*Pool)
+	if fresh == old || fresh.Generation != 2 {
+		t.Errorf("the start should run again: %+v", fresh)
+	}
+
+	if len(closed) != 1 || closed[0] != 1 {
+		t.Errorf("the old instance should be closed: %v", closed)
+	}
+
+	if err := b.Restart(Identity("broken")); err == nil || !strings.Contains(err.Error(), "stuck") {
+		t.Errorf("should return the close error: %v", err)
+	}
+
+	if err := b.Restart(Identity("nop")); err == nil {
+		t.Error("should reject an unknown manager")
+	}
+}
+
+func TestWarmAll(t *testing.T) {
+
+	b := NewBootstrap(nil)
+	b.Boot([]Manager{
+		NewManager(Identity("config"), func() string { return "config" }, nil),
+		{ID: Identity("db"), Start: func(config string) (int, error) { return 0, errors.New("refused") }},
+		{ID: Identity("cache"), Start: func(db int) bool { return true }},
+		{ID: Identity("queue"), Start: func() float64 { panic("no broker") }},
+	})
+	defer b.Release()
+
+	c := b.GetContainer()
+	c.Register(Identity("mailer"), func(config string) []byte { return []byte(config) })
+	c.RegisterWithArgs(Identity("client"), func(region string) *string { return &region })
+
+	errs := b.WarmAll()
+
+	if len(errs) != 3 || errs[Identity("db")] == nil || errs[Identity("cache")] == nil || errs[Identity("queue")] == nil {
+		t.Errorf("every broken builder should be reported: %v", errs)
+	}
+
+	for _, id := range []Identity{"config", "mailer"} {
+		if _, exists := c.store[id]; !exists {
+			t.Errorf("%s should be built", id)
+		}
+	}
+}
+
+func TestBuildOrder(t *testing.T) {
+
+	type Config struct{ DSN string }
+
+	var built []string
+	build := func(name string) func() string {
+		return func() string {
+			built = append(built, name)
+			return name
+		}
+	}
+
+	c := NewContainer()
+	c.Register(Identity("metrics"), build("metrics"), BuildOrder(3))
+	c.Register(Identity("migrate"), build("migrate"), BuildOrder(2))
+	c.Register(Identity("tracing"), build("tracing"), BuildOrder(2))
+	c.Register(Identity("config"), func() *Config {
+		built = append(built, "config")
+		return &Config{}
+	}, BuildOrder(1))
+	c.Register(Identity("db"), func(config *Config) int {
+		built = append(built, "db")
+		return 0
+	}, BuildOrder(-1))
+
+	NewBootstrap(c).WarmAll()
+
+	// db comes first but it's still built after the config it depends on
+	expected := []string{"config", "db", "migrate", "tracing", "metrics"}
+	if strings.Join(built, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected build order: %v", built)
+	}
+}
+
+func TestNewBootstrapChild(t *testing.T) {
+
+	type DB struct{ Name string }
+	type Plugin struct{ DB *DB }
+
+	closed := []string{}
+	parent := NewBootstrap(nil)
+	parent.Boot([]Manager{
+		NewManager(Identity("db"), func() *DB { return &DB{Name: "shared"} }, func(*DB) error {
+			closed = append(closed, "db")
+			return nil
+		}),
+	})
+	defer parent.Release()
+
+	child := NewBootstrapChild(parent)
+	child.WithEagerStart().Boot([]Manager{
+		{ID: Identity("plugin"), Start: func(db *DB) *Plugin { return &Plugin{DB: db} }, Close: func(*Container) error {
+			closed = append(closed, "plugin")
+			return nil
+		}},
+	})
+
+	plugin := child.GetContainer().MustGet(Identity("plugin")).(*Plugin)
+	if plugin.DB != parent.GetContainer().MustGet(Identity("db")) {
+		t.Error("the child manager should depend on the parent's service")
+	}
+
+	if err := child.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(closed, ",") != "plugin" {
+		t.Errorf("only the child managers should be closed: %v", closed)
+	}
+
+	if _, err := parent.GetContainer().Get(Identity("db")); err != nil {
+		t.Errorf("the parent should be untouched: %v", err)
+	}
+}
+
+func TestReleaseSkipsUnbuiltManager(t *testing.T) {
+
+	started := 0
+	closed := 0
+	b := NewBootstrap(nil)
+	b.Boot([]Manager{
+		NewManager(Identity("unused"), func() int {
+			started++
+			return 1
+		}, func(int) error {
+			closed++
+			return nil
+		}),
+	})
+
+	if err := b.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	if started != 0 || closed != 0 {
+		t.Errorf("an unused manager should not be built to be closed: started %d, closed %d", started, closed)
+	}
+}
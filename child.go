@@ -0,0 +1,44 @@
+package objectcommander
+
+import (
+	"errors"
+)
+
+// Child creates a container scoped under c, e.g. for a request. The child
+// has its own definitions and instances, and what it doesn't define is
+// resolved from c. The child inherits the logger, the panic handler, the
+// build timeout, the auto close and the reactive invalidation of c.
+func (c *Container) Child() *Container {
+	c.RLock()
+	defer c.RUnlock()
+
+	child := NewContainer()
+	child.parent = c
+	child.logger = c.logger
+	child.mustPanic = c.mustPanic
+	child.buildTimeout = c.buildTimeout
+	child.autoClose = c.autoClose
+	child.reactive = c.reactive
+
+	return child
+}
+
+// Parent returns the parent of a child container, it's nil for a root
+// container
+func (c *Container) Parent() *Container {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.parent
+}
+
+// ReleaseScope closes the instances built by a child container in the
+// reverse order of their creation. The instances resolved from the parent
+// are left untouched.
+func (c *Container) ReleaseScope() error {
+	if c.Parent() == nil {
+		return errors.New("ReleaseScope is only available for a child container")
+	}
+
+	return c.Close()
+}
@@ -0,0 +1,64 @@
+package objectcommander
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChild(t *testing.T) {
+
+	type DB struct{ Name string }
+	type Request struct {
+		DB *DB
+		ID string
+	}
+
+	var closed []string
+	closer := func(name string) func(interface{}) error {
+		return func(interface{}) error {
+			closed = append(closed, name)
+			return nil
+		}
+	}
+
+	parent := NewContainer()
+	parent.RegisterCloser(Identity("db"), func() *DB { return &DB{Name: "primary"} }, closer("db"))
+
+	child := parent.Child()
+	child.RegisterValue(Identity("requestID"), "42")
+	child.RegisterCloser(Identity("request"), func(db *DB, id string) *Request {
+		return &Request{DB: db, ID: id}
+	}, closer("request"))
+	child.RegisterCloser(Identity("tx"), func(r *Request) string { return "tx-" + r.ID }, closer("tx"))
+
+	request := child.MustGet(Identity("request")).(*Request)
+	if request.DB != parent.MustGet(Identity("db")) || request.ID != "42" {
+		t.Error("the child should resolve the parent's instances")
+	}
+
+	if _, err := child.GetByType(reflect.TypeOf(&DB{})); err != nil {
+		t.Error("the child should resolve the parent's types")
+	}
+
+	if _, err := parent.Get(Identity("request")); err == nil {
+		t.Error("the parent should not resolve the child's definitions")
+	}
+
+	child.MustGet(Identity("tx"))
+	if err := child.ReleaseScope(); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(closed, ",") != "tx,request" {
+		t.Errorf("only the child's instances should be closed in reverse order: %v", closed)
+	}
+
+	if _, exists := parent.store[Identity("db")]; !exists {
+		t.Error("the parent's instance should be kept")
+	}
+
+	if err := parent.ReleaseScope(); err == nil {
+		t.Error("ReleaseScope should be rejected for a root container")
+	}
+}
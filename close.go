@@ -0,0 +1,209 @@
+package objectcommander
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+var _ io.Closer = (*Container)(nil)
+
+// WithAutoClose makes Close also close the built instances which implement
+// io.Closer and have no closer registered, e.g. *os.File or net.Conn.
+func WithAutoClose() ContainerOption {
+	return func(c *Container) {
+		c.autoClose = true
+	}
+}
+
+// closeInstance is the closer used for an io.Closer instance
+func closeInstance(v interface{}) error {
+	return v.(io.Closer).Close()
+}
+
+// WithCloser sets the function which releases the instance when the
+// container is closed
+func WithCloser(closer func(interface{}) error) RegisterOption {
+	return func(d *definition) {
+		d.closer = closer
+	}
+}
+
+// RegisterCloser is Register with a closer which releases the instance
+// when the container is closed
+func (c *Container) RegisterCloser(name Identity, build Builder, closer func(interface{}) error, opts ...RegisterOption) error {
+	return c.Register(name, build, append(opts, WithCloser(closer))...)
+}
+
+// closingsOf returns the closer of an instance which isn't stored, e.g.
+// built by RegisterScoped, and the cleanup of its builder. They're closed
+// in the reverse order, so the cleanup runs after the closer. The caller
+// must hold the lock.
+func (c *Container) closingsOf(name Identity, obj interface{}, cleanup func()) []closing {
+	closings := []closing{}
+	if cleanup != nil {
+		closings = append(closings, closing{name: name, obj: obj, closer: cleanupCloser(cleanup)})
+	}
+
+	def := c.defs[name]
+	if def != nil && def.closer != nil {
+		closings = append(closings, closing{name: name, obj: obj, closer: def.closer})
+	} else if _, ok := obj.(io.Closer); ok && c.autoClose {
+		closings = append(closings, closing{name: name, obj: obj, closer: closeInstance})
+	}
+
+	return closings
+}
+
+// put stores the instance. The caller must hold the lock.
+func (c *Container) put(name Identity, obj interface{}) {
+	c.store[name] = obj
+	c.cached.Store(name, cachedInstance{obj: obj, def: c.defs[name]})
+	c.created = append(c.created, name)
+	c.rebind(name, obj)
+}
+
+// evict removes the instance from the store. The cleanup returned by its
+// builder is kept to run on Close since the instance may still be in use.
+// The caller must hold the lock.
+func (c *Container) evict(name Identity) {
+	delete(c.memo, name)
+	delete(c.scopedMemo, name)
+	if cleanup, exists := c.cleanups[name]; exists {
+		c.transients = append(c.transients, cleanup)
+		delete(c.cleanups, name)
+	}
+
+	if _, exists := c.store[name]; !exists {
+		return
+	}
+
+	delete(c.store, name)
+	c.cached.Delete(name)
+	for i, created := range c.created {
+		if created == name {
+			c.created = append(c.created[:i:i], c.created[i+1:]...)
+			break
+		}
+	}
+}
+
+// closing is an instance waiting to be closed
+type closing struct {
+	name   Identity
+	obj    interface{}
+	closer func(interface{}) error
+}
+
+// cleanupType is the reflect type of the cleanup returned by a builder
+var cleanupType = reflect.TypeOf(func() {})
+
+// cleanupCloser runs the cleanup returned by a builder as a closer
+func cleanupCloser(cleanup func()) func(interface{}) error {
+	return func(interface{}) error {
+		cleanup()
+		return nil
+	}
+}
+
+// track keeps the cleanup returned for an instance which isn't stored,
+// e.g. one built by Create, so it still runs on Close
+func (c *Container) track(res *resolution) {
+	if res.cleanup == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.transients = append(c.transients, res.cleanup)
+}
+
+// Close runs the closers of the built instances in the reverse order of
+// their creation and then clears the instances. With WithAutoClose, the
+// instances implementing io.Closer are closed as well. The cleanups
+// returned by the builders run after the closer of their instances, the
+// cleanups of the instances which aren't stored, e.g. built by Create, run
+// first, and then the instances of RegisterScoped and GetWithArgs. The
+// definitions are kept so the instances can be built again. It's safe to
+// be called more than once.
+func (c *Container) Close() error {
+	c.Lock()
+	closings := make([]closing, 0, len(c.created)+len(c.transients)+len(c.scoped)+len(c.memoized))
+	for i := len(c.transients) - 1; i >= 0; i-- {
+		closings = append(closings, closing{closer: cleanupCloser(c.transients[i])})
+	}
+
+	for i := len(c.scoped) - 1; i >= 0; i-- {
+		closings = append(closings, c.scoped[i])
+	}
+
+	for i := len(c.memoized) - 1; i >= 0; i-- {
+		closings = append(closings, c.memoized[i])
+	}
+
+	closedShared := map[*sharedValue]bool{}
+	for i := len(c.created) - 1; i >= 0; i-- {
+		name := c.created[i]
+		obj := c.store[name]
+
+		// a value registered under several identities is closed once
+		if def, exists := c.defs[name]; exists && def.shared != nil {
+			if closedShared[def.shared] {
+				continue
+			}
+			closedShared[def.shared] = true
+		}
+
+		if def, exists := c.defs[name]; exists && def.closer != nil {
+			closings = append(closings, closing{name: name, obj: obj, closer: def.closer})
+		} else if _, ok := obj.(io.Closer); ok && c.autoClose {
+			closings = append(closings, closing{name: name, obj: obj, closer: closeInstance})
+		}
+
+		if cleanup, exists := c.cleanups[name]; exists {
+			closings = append(closings, closing{name: name, obj: obj, closer: cleanupCloser(cleanup)})
+		}
+	}
+
+	c.store = make(map[Identity]interface{})
+	c.resetCached()
+	c.created = nil
+	c.cleanups = nil
+	c.transients = nil
+	c.scoped = nil
+	c.scopedMemo = nil
+	c.memoized = nil
+	c.memo = nil
+	c.Unlock()
+
+	errs := []error{}
+	for _, cl := range closings {
+		if err := cl.closer(cl.obj); err != nil {
+			errs = append(errs, fmt.Errorf("an error happens when closing %s: %w", cl.name, err))
+		}
+	}
+
+	return combineErrors(errs)
+}
+
+// combineErrors merges the errors into one error, it returns nil if there
+// is no error
+func combineErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	if len(errs) == 1 {
+		return errs[0]
+	}
+
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+
+	return errors.New(strings.Join(msgs, "; "))
+}
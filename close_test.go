@@ -0,0 +1,226 @@
+package objectcommander
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestClose(t *testing.T) {
+
+	var closed []string
+	closer := func(v interface{}) error {
+		closed = append(closed, v.(string))
+		return nil
+	}
+
+	c := NewContainer()
+	c.RegisterCloser(Identity("config"), func() string { return "config" }, closer)
+	c.RegisterCloser(Identity("db"), func() string { return "db" }, closer)
+	c.RegisterCloser(Identity("cache"), func() string { return "cache" }, closer)
+	c.RegisterCloser(Identity("unused"), func() string { return "unused" }, closer)
+
+	c.MustGet(Identity("db"))
+	c.MustGet(Identity("config"))
+	c.MustGet(Identity("cache"))
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(closed, ",") != "cache,config,db" {
+		t.Errorf("should close in the reverse order of creation: %v", closed)
+	}
+
+	if len(c.store) != 0 {
+		t.Error("instances should be cleared")
+	}
+
+	if err := c.Close(); err != nil || len(closed) != 3 {
+		t.Error("closing twice should be safe")
+	}
+
+	// the definitions are kept
+	if c.MustGet(Identity("db")).(string) != "db" {
+		t.Error("failed to rebuild after close")
+	}
+}
+
+func TestCloseAggregatesErrors(t *testing.T) {
+
+	c := NewContainer()
+	failed := func(v interface{}) error {
+		return errors.New("broken " + v.(string))
+	}
+
+	c.RegisterCloser(Identity("db"), func() string { return "db" }, failed)
+	c.RegisterCloser(Identity("cache"), func() string { return "cache" }, failed)
+	c.MustGet(Identity("db"))
+	c.MustGet(Identity("cache"))
+
+	err := c.Close()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if !strings.Contains(err.Error(), "broken db") || !strings.Contains(err.Error(), "broken cache") {
+		t.Errorf("errors should be aggregated: %s", err)
+	}
+}
+
+// fakeConn is an io.Closer which records the closing order
+type fakeConn struct {
+	name   string
+	closed *[]string
+}
+
+func (f *fakeConn) Close() error {
+	*f.closed = append(*f.closed, f.name)
+	return nil
+}
+
+func TestAutoClose(t *testing.T) {
+
+	var closed []string
+	conn := func(name string) func() *fakeConn {
+		return func() *fakeConn { return &fakeConn{name: name, closed: &closed} }
+	}
+
+	c := NewContainer(WithAutoClose())
+	c.Register(Identity("primary"), conn("primary"))
+	c.Register(Identity("replica"), conn("replica"))
+	c.Register(Identity("unused"), conn("unused"))
+
+	c.MustGet(Identity("primary"))
+	c.MustGet(Identity("replica"))
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(closed, ",") != "replica,primary" {
+		t.Errorf("only the built closers should be closed in reverse order: %v", closed)
+	}
+
+	closed = nil
+	manual := NewContainer()
+	manual.Register(Identity("primary"), conn("primary"))
+	manual.MustGet(Identity("primary"))
+	manual.Close()
+
+	if len(closed) != 0 {
+		t.Error("auto close should be opt-in")
+	}
+}
+
+func TestBuilderCleanup(t *testing.T) {
+
+	var cleaned []string
+	c := NewContainer()
+	c.Register(Identity("pool"), func() (string, func(), error) {
+		return "pool", func() { cleaned = append(cleaned, "pool") }, nil
+	})
+	c.Register(Identity("session"), func(pool string) (int, func(), error) {
+		return 1, func() { cleaned = append(cleaned, "session") }, nil
+	})
+	c.Register(Identity("broken"), func() (bool, func(), error) {
+		return false, nil, errors.New("failed")
+	})
+	c.Register(Identity("plain"), func() (float64, error) { return 1.5, nil })
+
+	if c.MustGet(Identity("pool")).(string) != "pool" {
+		t.Error("the value should be the first return")
+	}
+
+	if _, err := c.Create(Identity("session")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Get(Identity("broken")); err == nil {
+		t.Error("the error of the builder should be returned")
+	}
+
+	if c.MustGet(Identity("plain")).(float64) != 1.5 {
+		t.Error("a two-return builder should still work")
+	}
+
+	if len(cleaned) != 0 {
+		t.Fatalf("the cleanups should not run before close: %v", cleaned)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(cleaned, ",") != "session,pool" {
+		t.Errorf("the cleanups should run on close in reverse order: %v", cleaned)
+	}
+
+	cleaned = nil
+	c.Close()
+	if len(cleaned) != 0 {
+		t.Errorf("the cleanups should only run once: %v", cleaned)
+	}
+}
+
+func TestEvictedCleanupRunsOnClose(t *testing.T) {
+
+	type Conn struct{ ID int }
+
+	cleaned := 0
+	built := 0
+	c := NewContainer()
+	c.Register(Identity("conn"), func() (*Conn, func()) {
+		built++
+		return &Conn{ID: built}, func() { cleaned++ }
+	})
+
+	c.MustGet(Identity("conn"))
+	c.Invalidate(Identity("conn"))
+	c.MustGet(Identity("conn"))
+
+	if cleaned != 0 {
+		t.Error("the cleanup of the evicted instance should wait for Close")
+	}
+
+	c.Close()
+	if cleaned != 2 {
+		t.Errorf("the cleanups of both instances should run on Close: %d", cleaned)
+	}
+}
+
+func TestChannelSingleton(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("shutdown"), func() (chan struct{}, func()) {
+		done := make(chan struct{})
+		return done, func() { close(done) }
+	})
+	c.Register(Identity("events"), func() <-chan string {
+		events := make(chan string, 1)
+		events <- "started"
+		return events
+	})
+
+	shutdown := c.MustGet(Identity("shutdown")).(chan struct{})
+	if c.MustGet(Identity("shutdown")) != shutdown {
+		t.Error("the channel should be cached")
+	}
+
+	var events <-chan string
+	if err := c.Assign(&events); err != nil || <-events != "started" {
+		t.Errorf("the channel should be assigned by its type: %v", err)
+	}
+
+	var received chan struct{}
+	if err := c.Invoke(func(ch chan struct{}) { received = ch }); err != nil || received != shutdown {
+		t.Errorf("the channel should be resolved as a param: %v", err)
+	}
+
+	c.Close()
+	select {
+	case <-shutdown:
+	default:
+		t.Error("the cleanup should close the channel")
+	}
+}
@@ -0,0 +1,25 @@
+// Package commandertest provides helpers for testing the wiring of a
+// container. It's kept out of objectcommander so the production builds
+// don't import testing.
+package commandertest
+
+import (
+	"testing"
+
+	objectcommander "github.com/jgebang/object-commander"
+)
+
+// AssertResolvable fails the test if the container has a parameter which
+// can't be resolved or a dependency cycle, see Container.Validate. Nothing
+// is built.
+//
+//	func TestWiring(t *testing.T) {
+//		commandertest.AssertResolvable(t, newContainer())
+//	}
+func AssertResolvable(t testing.TB, c *objectcommander.Container) {
+	t.Helper()
+
+	if err := c.Validate(); err != nil {
+		t.Errorf("the container can't resolve its dependencies: %s", err)
+	}
+}
@@ -0,0 +1,45 @@
+package commandertest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	objectcommander "github.com/jgebang/object-commander"
+)
+
+// recordTB keeps the failures instead of failing the test
+type recordTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordTB) Helper() {}
+
+func (r *recordTB) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestAssertResolvable(t *testing.T) {
+
+	type DB struct{ DSN string }
+
+	c := objectcommander.NewContainer()
+	c.Register(objectcommander.Identity("repo"), func(db *DB) string { return "repo" })
+
+	tb := &recordTB{TB: t}
+	AssertResolvable(tb, c)
+
+	if len(tb.failures) != 1 || !strings.Contains(tb.failures[0], "repo depends on") {
+		t.Fatalf("the missing dependency should be reported: %v", tb.failures)
+	}
+
+	c.Register(objectcommander.Identity("db"), func() *DB { return &DB{} })
+
+	tb = &recordTB{TB: t}
+	AssertResolvable(tb, c)
+
+	if len(tb.failures) != 0 {
+		t.Errorf("the wiring should be resolvable: %v", tb.failures)
+	}
+}
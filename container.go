@@ -1,10 +1,14 @@
 package objectcommander
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Identity is a unique name for container resource and bootstrap
@@ -12,7 +16,8 @@ type Identity string
 
 // AlreadyRegisteredError is an error for reregisteration
 type AlreadyRegisteredError struct {
-	msg string
+	Name Identity // Name is the identity which was already registered
+	msg  string
 }
 
 // Error returns the error message
@@ -23,176 +28,844 @@ func (a AlreadyRegisteredError) Error() string {
 // Builder is a function to generate the resrouce
 type Builder interface{}
 
+// definition describes how to build a resource and how the container
+// treats the instance once it's built
+type definition struct {
+	build           Builder
+	seq             uint64 // seq is the order of the registration
+	priority        int
+	buildOrder      int // buildOrder orders the independent builds of Warm and WarmAll, see BuildOrder
+	tags            []string
+	provider        func() Builder // provider generates the build lazily, see RegisterLazyDef
+	providerOnce    *sync.Once
+	providerErr     error
+	closer          func(interface{}) error
+	returnCopy      bool
+	weak            bool
+	atomic          bool
+	variadic        []reflect.Value // variadic is passed to a variadic builder, see RegisterVariadic
+	idempotentStart bool
+	withArgs        bool         // withArgs takes the leading args from GetWithArgs, see RegisterWithArgs
+	shared          *sharedValue // shared is the value registered by RegisterValueAs
+	typed           typedBuild   // typed builds without reflection, see RegisterFunc0
+	sealed          bool
+	retry           retryPolicy // retry runs a failing builder again, see RegisterWithRetry
+	warmup          func(interface{}) error
+	scopeKey        func(ctx context.Context) string // scopeKey caches the instances per key, see RegisterScoped
+	built           func()                           // built is called once the instance is stored, see Boot
+}
+
 // NewContainer creates a new container
-func NewContainer() *Container {
-	return &Container{
+func NewContainer(opts ...ContainerOption) *Container {
+	c := &Container{
 		store:          make(map[Identity]interface{}),
-		defs:           make(map[Identity]Builder),
+		defs:           make(map[Identity]*definition),
 		typeToIdentity: make(map[reflect.Type][]Identity),
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// NewContainerWith creates a new container with the definitions registered.
+// The definitions are registered in the order of their identities so the
+// type resolution is deterministic.
+func NewContainerWith(defs map[Identity]Builder) (*Container, error) {
+	c := NewContainer()
+
+	names := make([]Identity, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+
+	for _, name := range names {
+		if err := c.Register(name, defs[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
 }
 
 // Container is global object accessor and can be used as dependency injection
 type Container struct {
-	defs           map[Identity]Builder
+	defs           map[Identity]*definition
 	typeToIdentity map[reflect.Type][]Identity
 	store          map[Identity]interface{}
+	created        []Identity // the identities in store by the order of creation
+	logger         Logger
+	mustPanic      func(err error) interface{}
+	onces          map[Identity]*sync.Once
+	borrows        map[Identity]int
+	buildTimeout   time.Duration
+	seq            uint64
+	autoClose      bool
+	flights        map[flightKey]*flight
+	waiting        map[int64]flightKey                      // waiting are the flights the goroutines wait for, see waitsFor
+	memo           map[Identity]map[interface{}]interface{} // the instances built by GetWithArgs
+	scopedMemo     map[Identity]map[string]interface{}      // the instances built by RegisterScoped
+	scoped         []closing                                // scoped are the instances of RegisterScoped to be closed
+	memoized       []closing                                // memoized are the instances of GetWithArgs to be closed
+	cleanups       map[Identity]func()                      // the cleanups returned by the builders of store
+	transients     []func()                                 // the cleanups of the instances which aren't stored
+	parent         *Container
+	frozen         bool
+	reactive       bool                           // reactive invalidates the dependents, see WithReactiveInvalidation
+	dependents     map[Identity]map[Identity]bool // dependents are the instances built from an identity
+	paramCache     paramCache
+	converters     map[reflect.Type][]converter // converters are keyed by the type they convert to
+	defaults       map[reflect.Type]Identity    // defaults are the identities chosen to resolve the types
+	strategy       ResolutionStrategy
+	explicitBuild  bool         // explicitBuild disables the lazy build, see WithExplicitBuild
+	cached         sync.Map     // cached mirrors store for the lock-free reads of Get
+	recorder       atomic.Value // recorder is the *recorder of RecordResolutions
+	beforeBuild    []func(name Identity, deps []Identity)
+	bindings       map[Identity][]reflect.Value // bindings are the targets assigned on every build, see BindField
 	sync.RWMutex
 }
 
 // checkBuilderSignature is an helper function to check the interface if matched the format
 // for generating the resource.
 func checkBuilderSignature(ftype reflect.Type) error {
+	_, _, err := builderOutputs(ftype)
+	return err
+}
+
+// builderOutputs returns the positions of the value and the error among
+// the returns of the builder. A builder returns one value and optionally
+// an error in any position, errIndex is -1 if it doesn't return an error.
+// A builder may also return (T, func()) or (T, func(), error) whose func()
+// is the cleanup of the value.
+func builderOutputs(ftype reflect.Type) (valueIndex int, errIndex int, err error) {
 
 	if ftype == nil {
-		return errors.New("can't invoke nil type")
+		return 0, 0, errors.New("can't invoke nil type")
 	}
 
 	if ftype.Kind() != reflect.Func {
-		return fmt.Errorf("can't invoke non-function: %s", ftype)
+		return 0, 0, fmt.Errorf("can't invoke non-function: %s", ftype)
 	}
 
-	if ftype.NumOut() != 1 {
-		return fmt.Errorf("expect builder function returns one value")
+	switch ftype.NumOut() {
+	case 1:
+		return 0, -1, nil
+	case 2:
+		first, second := ftype.Out(0).Implements(errorType), ftype.Out(1).Implements(errorType)
+		if first != second {
+			if first {
+				return 1, 0, nil
+			}
+			return 0, 1, nil
+		}
+
+		// the value and its cleanup, e.g. a channel and closing it
+		if !first && ftype.Out(1) == cleanupType {
+			return 0, -1, nil
+		}
+	case 3:
+		// the value, its cleanup and an error, see Close
+		if !ftype.Out(0).Implements(errorType) && ftype.Out(1) == cleanupType && ftype.Out(2).Implements(errorType) {
+			return 0, 2, nil
+		}
 	}
 
-	return nil
+	return 0, 0, fmt.Errorf("expect builder function returns one value and an optional error: %s", ftype)
+}
+
+// ValidateBuilder checks whether the build function has the shape of a
+// builder without registering it. It returns the same errors the
+// container reports when building the resource.
+func ValidateBuilder(build Builder) error {
+	return checkBuilderSignature(reflect.TypeOf(build))
 }
 
-func (c *Container) bind(b Builder) (*reflect.Value, error) {
+func (c *Container) bind(res *resolution, name Identity, def *definition, overrides map[reflect.Type]interface{}) (*reflect.Value, error) {
+	b := def.build
 	ftype := reflect.TypeOf(b)
 
-	if err := checkBuilderSignature(ftype); err != nil {
+	valueIndex, errIndex, err := builderOutputs(ftype)
+	if err != nil {
 		return nil, err
 	}
 
-	args, err := buildParams(ftype, c)
+	c.RLock()
+	hooks := c.beforeBuild
+	timeout := c.buildTimeout
+	c.RUnlock()
+	res.trackDeps = len(hooks) > 0
+
+	if def.typed != nil && len(hooks) == 0 && timeout <= 0 && overrides == nil {
+		obj, err := def.typed(c, res)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the dependencies of %s: %w", name, err)
+		}
+
+		ret := reflect.ValueOf(&obj).Elem()
+		return &ret, nil
+	}
+
+	args, err := buildParams(ftype, c, res, overrides)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the dependencies of %s: %w", name, err)
+	}
+	args = append(args, def.variadic...)
+
+	for _, hook := range hooks {
+		hook(name, res.deps)
+	}
+
+	ret, err := c.invokeWithRetry(res, name, def, reflect.ValueOf(b), args, errIndex)
 	if err != nil {
 		return nil, err
 	}
-	ret := invoker(reflect.ValueOf(b), args)
-	return &ret[0], nil
+
+	if len(ret) > 1 && ftype.Out(1) == cleanupType && !ret[1].IsNil() {
+		res.cleanup = ret[1].Interface().(func())
+	}
+
+	return &ret[valueIndex], nil
 }
 
-// Register add the definition to builders
-func (c *Container) Register(name Identity, build Builder) error {
+// Register add the definition to builders. The behavior of the instance
+// can be customized by the options.
+func (c *Container) Register(name Identity, build Builder, opts ...RegisterOption) error {
+
+	if err := ValidateBuilder(build); err != nil {
+		return err
+	}
+
+	c.Lock()
+	if c.frozen {
+		c.Unlock()
+		return FrozenContainerError{Op: "register", Name: name}
+	}
 
-	c.RLock()
 	if _, exists := c.defs[name]; exists {
 
-		c.RUnlock()
+		c.Unlock()
 		return AlreadyRegisteredError{
-			msg: fmt.Sprintf("%s was already registered", name),
+			Name: name,
+			msg:  fmt.Sprintf("%s was already registered", name),
 		}
 	}
-	c.RUnlock()
 
-	c.Lock()
-	fn := reflect.TypeOf((build))
-	retType := fn.Out(0)
+	def := &definition{build: build}
+	for _, opt := range opts {
+		opt(def)
+	}
+	if err := def.checkCopy(); err != nil {
+		c.Unlock()
+		return err
+	}
 
-	c.defs[name] = build
+	c.seq++
+	def.seq = c.seq
+	retType := def.outType()
+	registered := c.typeToIdentity[retType]
+
+	c.defs[name] = def
 	c.typeToIdentity[retType] = append(
 		c.typeToIdentity[retType],
 		name)
+	c.resetParamCache()
 
 	c.Unlock()
 
+	// registering the same type twice is legal but it's often a mistake
+	if len(registered) > 0 {
+		c.logf("type %s registered as %s is already registered as %s", retType, name, registered[0])
+	}
+
 	return nil
 }
 
-func pop(source []Identity, target Identity) []Identity {
-	var index = 0
+// RegisterValue registers an already built value. Values of the same
+// type can only be told apart by their identities.
+func (c *Container) RegisterValue(name Identity, value interface{}, opts ...RegisterOption) error {
+	if value == nil {
+		return fmt.Errorf("the value of %s should not be nil", name)
+	}
+
+	v := reflect.ValueOf(value)
+	ftype := reflect.FuncOf(nil, []reflect.Type{v.Type()}, false)
+	build := reflect.MakeFunc(ftype, func([]reflect.Value) []reflect.Value {
+		return []reflect.Value{v}
+	})
+
+	return c.Register(name, build.Interface(), opts...)
+}
+
+// RegisterVariadic registers a variadic builder, e.g. a constructor taking
+// functional options, with the variadic args it should be called with.
+// The other args are resolved from the container as usual.
+func (c *Container) RegisterVariadic(name Identity, build Builder, variadicArgs ...interface{}) error {
+	if err := ValidateBuilder(build); err != nil {
+		return err
+	}
+
+	ftype := reflect.TypeOf(build)
+	if !ftype.IsVariadic() {
+		return fmt.Errorf("builder of %s is not variadic: %s", name, ftype)
+	}
+
+	elemType := ftype.In(ftype.NumIn() - 1).Elem()
+	values := make([]reflect.Value, 0, len(variadicArgs))
+	for i, arg := range variadicArgs {
+		if arg == nil {
+			values = append(values, reflect.Zero(elemType))
+			continue
+		}
+
+		if argType := reflect.TypeOf(arg); !argType.AssignableTo(elemType) {
+			return fmt.Errorf("variadic arg %d of %s is a %s which is not assignable to %s", i, name, argType, elemType)
+		}
+		values = append(values, reflect.ValueOf(arg))
+	}
+
+	return c.Register(name, build, func(d *definition) {
+		d.variadic = values
+	})
+}
+
+// RegisterIf registers the definition only when cond is true, otherwise
+// it does nothing.
+func (c *Container) RegisterIf(cond bool, name Identity, build Builder, opts ...RegisterOption) error {
+	if !cond {
+		return nil
+	}
+
+	return c.Register(name, build, opts...)
+}
+
+// RegisterIfFunc works like RegisterIf but the condition is evaluated by
+// calling cond when registering.
+func (c *Container) RegisterIfFunc(cond func() bool, name Identity, build Builder, opts ...RegisterOption) error {
+	return c.RegisterIf(cond(), name, build, opts...)
+}
 
+func pop(source []Identity, target Identity) []Identity {
 	for i, value := range source {
 		if value == target {
-			index = i
-			break
+			return append(source[:i:i], source[i+1:]...)
 		}
 	}
 
-	return append(source[:index], source[index+1:]...)
+	return source
 }
 
 // Unregister removes the definition from the builders
-func (c *Container) Unregister(name Identity) {
+func (c *Container) Unregister(name Identity) error {
 	c.Lock()
 	defer c.Unlock()
 
-	builder := c.defs[name]
-	fn := reflect.TypeOf(builder)
-	retType := fn.Out(0)
+	if c.frozen {
+		return FrozenContainerError{Op: "unregister", Name: name}
+	}
+
+	def, exists := c.defs[name]
+	if !exists {
+		return nil
+	}
+
+	if def.sealed {
+		return SealedError{Op: "unregister", Name: name}
+	}
 
-	pop(c.typeToIdentity[retType], name)
 	delete(c.defs, name)
-	delete(c.store, name)
+	c.dropShared(name, def)
+	if retType := def.outType(); retType != nil {
+		c.typeToIdentity[retType] = pop(c.typeToIdentity[retType], name)
+	}
+	c.evict(name)
+	c.resetParamCache()
+
+	return nil
+}
+
+// Override replaces the definition of the identity and drops its cached
+// instance, the next Get builds it with the new builder. The identity is
+// registered if it doesn't exist yet. The instances already built from the
+// old instance are not rebuilt.
+func (c *Container) Override(name Identity, build Builder, opts ...RegisterOption) error {
+	if err := ValidateBuilder(build); err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.frozen {
+		return FrozenContainerError{Op: "override", Name: name}
+	}
+
+	def := &definition{build: build}
+	for _, opt := range opts {
+		opt(def)
+	}
+	if err := def.checkCopy(); err != nil {
+		return err
+	}
+
+	if old, exists := c.defs[name]; exists {
+		if old.sealed {
+			return SealedError{Op: "override", Name: name}
+		}
+
+		delete(c.defs, name)
+		c.dropShared(name, old)
+		if retType := old.outType(); retType != nil {
+			c.typeToIdentity[retType] = pop(c.typeToIdentity[retType], name)
+		}
+		c.evict(name)
+		def.seq = old.seq
+	} else {
+		c.seq++
+		def.seq = c.seq
+	}
+
+	retType := def.outType()
+
+	c.defs[name] = def
+	c.typeToIdentity[retType] = append(c.typeToIdentity[retType], name)
+	c.resetParamCache()
+
+	return nil
+}
+
+// has reports whether the identity is registered in the container or its
+// parents
+func (c *Container) has(name Identity) bool {
+	c.RLock()
+	_, exists := c.defs[name]
+	parent := c.parent
+	c.RUnlock()
+
+	return exists || (parent != nil && parent.has(name))
+}
+
+// buildable returns the identities which Get can build ordered by the
+// registration, the definitions taking args from GetWithArgs are left out
+func (c *Container) buildable() []Identity {
+	c.RLock()
+	defer c.RUnlock()
+
+	names := make([]Identity, 0, len(c.defs))
+	for name, def := range c.defs {
+		if !def.withArgs {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		left, right := c.defs[names[i]], c.defs[names[j]]
+		if left.buildOrder != right.buildOrder {
+			return left.buildOrder < right.buildOrder
+		}
+
+		return left.seq < right.seq
+	})
+
+	return names
+}
+
+// buildOrderOf returns the build order of the definition, it's 0 if the
+// identity isn't registered
+func (c *Container) buildOrderOf(name Identity) int {
+	c.RLock()
+	defer c.RUnlock()
+
+	if def, exists := c.defs[name]; exists {
+		return def.buildOrder
+	}
+
+	return 0
+}
+
+// ForEach calls fn with the identity and the type of every registered
+// definition under the read lock, and stops once fn returns false. The
+// order is unspecified and t is nil for a lazy definition whose builder
+// isn't provided yet. fn must not call the methods which mutate the
+// container otherwise it will deadlock.
+func (c *Container) ForEach(fn func(name Identity, t reflect.Type) bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	for name, def := range c.defs {
+		if !fn(name, def.outType()) {
+			return
+		}
+	}
 }
 
-// FlushALL clears all registered builders
+// Len returns the number of the registered definitions and the number of
+// the singletons which are built, both taken at the same time. The
+// instances built by GetWithArgs, RegisterScoped and Create aren't counted,
+// nor are the definitions and instances of the parent.
+func (c *Container) Len() (defs int, instances int) {
+	c.RLock()
+	defer c.RUnlock()
+
+	return len(c.defs), len(c.store)
+}
+
+// FlushALL clears all registered builders along with their instances,
+// which are dropped without being closed, and the fields bound to them by
+// BindField. The sealed definitions and their instances are kept and a
+// warning is logged, see Sealed. The configuration of the container is
+// kept: the options, the logger, the panic handler, the build timeout,
+// the BeforeBuild hooks, the converters and the default identities, see
+// ResetAll to clear them as well.
 func (c *Container) FlushALL() {
-	c.defs = make(map[Identity]Builder)
-	c.store = make(map[Identity]interface{})
-	c.typeToIdentity = make(map[reflect.Type][]Identity)
+	c.Lock()
+	defs := make(map[Identity]*definition)
+	store := make(map[Identity]interface{})
+	cleanups := make(map[Identity]func())
+	sealed := []Identity{}
+	for name, def := range c.defs {
+		if !def.sealed {
+			continue
+		}
+
+		sealed = append(sealed, name)
+		defs[name] = def
+		if obj, exists := c.store[name]; exists {
+			store[name] = obj
+		}
+		if cleanup, exists := c.cleanups[name]; exists {
+			cleanups[name] = cleanup
+		}
+	}
+
+	typeToIdentity := make(map[reflect.Type][]Identity)
+	for t, ids := range c.typeToIdentity {
+		for _, id := range ids {
+			if _, exists := defs[id]; exists {
+				typeToIdentity[t] = append(typeToIdentity[t], id)
+			}
+		}
+	}
+
+	created := []Identity{}
+	for _, name := range c.created {
+		if _, exists := store[name]; exists {
+			created = append(created, name)
+		}
+	}
+
+	bindings := make(map[Identity][]reflect.Value)
+	for name, targets := range c.bindings {
+		if _, exists := defs[name]; exists {
+			bindings[name] = targets
+		}
+	}
+
+	c.defs = defs
+	c.store = store
+	c.resetCached()
+	for name, obj := range store {
+		c.cached.Store(name, cachedInstance{obj: obj, def: defs[name]})
+	}
+	c.created = created
+	c.memo = nil
+	c.scopedMemo = nil
+	c.scoped = nil
+	c.memoized = nil
+	c.cleanups = cleanups
+	c.transients = nil
+	c.dependents = nil
+	c.bindings = bindings
+	c.typeToIdentity = typeToIdentity
+	c.resetParamCache()
+	c.Unlock()
+
+	if len(sealed) > 0 {
+		sort.Slice(sealed, func(i, j int) bool { return sealed[i] < sealed[j] })
+		c.logf("FlushALL keeps the sealed identities %v", sealed)
+	}
+}
+
+// ResetAll is FlushALL which also clears the BeforeBuild hooks, the
+// converters, the default identities and the resolution strategy, so a
+// container reused across the test cases doesn't leak them. The options
+// given to NewContainer, the logger, the panic handler and the build
+// timeout are still kept.
+func (c *Container) ResetAll() {
+	c.FlushALL()
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.beforeBuild = nil
+	c.converters = nil
+	c.defaults = nil
+	c.strategy = nil
+	c.resetParamCache()
 }
 
 // GetByType works like get but instead of getting instance by the identity,
 // this will allow you give a type and automatically induct the identity
-// for you
+// for you. If there are several identities registered with the type, the
+// default identity wins, see SetDefaultIdentity, then the one with the
+// highest priority and then the first registered one, unless there is a
+// resolution strategy, see SetResolutionStrategy. An interface which isn't
+// registered itself is resolved by the types implementing it, unless it's
+// the empty interface.
 func (c *Container) GetByType(t reflect.Type) (interface{}, error) {
-
-	if len(c.typeToIdentity[t]) == 0 {
-		return nil, fmt.Errorf("there is no instance registered with type: %s", t)
+	id, exists, err := c.lookup(t)
+	if err != nil {
+		return nil, err
 	}
 
-	id := c.typeToIdentity[t][0]
+	if !exists {
+		return nil, unregisteredTypeError{t: t}
+	}
 
 	return c.Get(id)
 }
 
+// GetWhere resolves the first identity registered with the type which
+// satisfies match, e.g. the one ending with the name of the environment.
+// Like GetByType, the identities are tried by the priority and then the
+// order of registration.
+func (c *Container) GetWhere(t reflect.Type, match func(name Identity) bool) (interface{}, error) {
+	c.RLock()
+	ids := c.sortByPriority(append([]Identity(nil), c.typeToIdentity[t]...))
+	parent := c.parent
+	c.RUnlock()
+
+	for _, id := range ids {
+		if match(id) {
+			return c.Get(id)
+		}
+	}
+
+	if parent != nil {
+		return parent.GetWhere(t, match)
+	}
+
+	return nil, fmt.Errorf("there is no instance registered with type %s which matches", t)
+}
+
+// unregisteredTypeError is returned when nothing resolves the type
+type unregisteredTypeError struct {
+	t reflect.Type
+}
+
+func (u unregisteredTypeError) Error() string {
+	return fmt.Sprintf("there is no instance registered with type: %s", u.t)
+}
+
+// lookup chooses the identity to resolve the type. exists is false if
+// nothing is registered with the type, the error is returned by the
+// resolution strategy.
+func (c *Container) lookup(t reflect.Type) (id Identity, exists bool, err error) {
+	c.RLock()
+	strategy := c.strategy
+	var candidates []Identity
+	if strategy == nil {
+		id, exists = c.lookupLocked(t)
+	} else {
+		candidates = c.candidatesLocked(t)
+		exists = len(candidates) > 0
+	}
+	parent := c.parent
+	c.RUnlock()
+
+	if !exists && parent != nil {
+		return parent.lookup(t)
+	}
+
+	if strategy == nil || !exists {
+		return id, exists, nil
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0], true, nil
+	}
+
+	if id, err = strategy(t, candidates); err != nil {
+		return "", true, err
+	}
+
+	for _, candidate := range candidates {
+		if candidate == id {
+			return id, true, nil
+		}
+	}
+
+	return "", true, fmt.Errorf("the resolution strategy chose %s which isn't a candidate for %s", id, t)
+}
+
+// lookupLocked is the default choice of lookup without a resolution
+// strategy, the caller holds the lock
+func (c *Container) lookupLocked(t reflect.Type) (Identity, bool) {
+	ids := c.typeToIdentity[t]
+	if len(ids) == 0 {
+		ids = c.implementing(t)
+	}
+
+	if len(ids) == 0 {
+		return "", false
+	}
+
+	if preferred, exists := c.defaults[t]; exists {
+		for _, id := range ids {
+			if id == preferred {
+				return id, true
+			}
+		}
+	}
+
+	chosen := ids[0]
+	for _, id := range ids[1:] {
+		if c.defs[id].priority > c.defs[chosen].priority {
+			chosen = id
+		}
+	}
+
+	return chosen, true
+}
+
+// candidatesLocked returns the identities which can resolve the type
+// ordered by the priority and then the order of registration. The caller
+// must hold the lock.
+func (c *Container) candidatesLocked(t reflect.Type) []Identity {
+	ids := c.typeToIdentity[t]
+	if len(ids) == 0 {
+		return c.sortByPriority(c.implementing(t))
+	}
+
+	return c.sortByPriority(append([]Identity(nil), ids...))
+}
+
+// implementing returns the identities registered with a type implementing
+// the interface, including a larger interface embedding it, ordered by the
+// registration. An empty interface isn't matched since every registered
+// type implements it. The caller must hold the lock.
+func (c *Container) implementing(iface reflect.Type) []Identity {
+	if iface.Kind() != reflect.Interface || iface.NumMethod() == 0 {
+		return nil
+	}
+
+	ids := []Identity{}
+	for t, registered := range c.typeToIdentity {
+		if t != nil && t.Implements(iface) {
+			ids = append(ids, registered...)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return c.defs[ids[i]].seq < c.defs[ids[j]].seq })
+
+	return ids
+}
+
 // MustGet is an helper for Get without returning error. It will
 // panic once if there is an error happens so pleasure ensure you
 // are knowing the instance is actually registered.
 func (c *Container) MustGet(name Identity) interface{} {
 	result, err := c.Get(name)
 	if err != nil {
-		panic(err)
+		c.panicWith(err)
 	}
 
 	return result
 }
 
-// Get to get a singleton resource
-func (c *Container) Get(name Identity) (interface{}, error) {
+// GetOr works like Get but returns the fallback instead of an error
+// when the instance can't be resolved.
+func (c *Container) GetOr(name Identity, fallback interface{}) interface{} {
+	result, err := c.Get(name)
+	if err != nil {
+		return fallback
+	}
+
+	return result
+}
+
+// Peek returns the instance if it's already built without building it,
+// e.g. to close a resource only if it was used.
+func (c *Container) Peek(name Identity) (interface{}, bool) {
 	c.RLock()
+	obj, built := c.store[name]
+	def, local := c.defs[name]
+	parent := c.parent
+	c.RUnlock()
 
-	if obj, exists := c.store[name]; exists {
-		c.RUnlock()
-		return obj, nil
+	if built {
+		return def.instance(obj), true
 	}
-	c.RUnlock()
 
-	ret, err := c.create(name)
+	if !local && parent != nil {
+		return parent.Peek(name)
+	}
+
+	return nil, false
+}
+
+// TryGet is the best-effort version of Get for non-critical paths. It
+// returns false instead of an error when the instance can't be resolved,
+// and it doesn't panic even if the builder does.
+func (c *Container) TryGet(name Identity) (result interface{}, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, ok = nil, false
+		}
+	}()
+
+	result, err := c.Get(name)
 	if err != nil {
-		return nil, err
+		return nil, false
 	}
 
-	obj := ret.Interface()
+	return result, true
+}
 
-	c.Lock()
-	defer c.Unlock()
-	c.store[name] = obj
+// Get to get a singleton resource. Concurrent callers of an instance
+// which isn't built yet share one build.
+func (c *Container) Get(name Identity) (interface{}, error) {
+	// skip allocating a resolution for a built instance
+	if c.recording() == nil {
+		if obj, exists := c.loadCached(name); exists {
+			return obj, nil
+		}
+	}
 
-	return obj, nil
+	return c.get(newResolution(context.Background()), name)
 }
 
-func (c *Container) create(name Identity) (*reflect.Value, error) {
-	builder, exists := c.defs[name]
+// create builds a new instance from the definition. The lock must not be
+// held by the caller because the builder may resolve its dependencies
+// from the container.
+func (c *Container) create(res *resolution, name Identity) (*reflect.Value, error) {
+	c.RLock()
+	def, exists := c.defs[name]
+	parent := c.parent
+	c.RUnlock()
+
+	if !exists && parent != nil {
+		return parent.create(res, name)
+	}
 
 	if !exists {
 		return nil, fmt.Errorf("%s was not registered", name)
 	}
 
-	ret, err := c.bind(builder)
+	if def.withArgs && res.literals == nil {
+		return nil, fmt.Errorf("%s is built with arguments, use GetWithArgs", name)
+	}
+
+	if err := c.provide(name, def); err != nil {
+		return nil, err
+	}
+
+	var overrides map[reflect.Type]interface{}
+	if def.idempotentStart {
+		overrides = map[reflect.Type]interface{}{onceType: c.startOnce(name)}
+	}
+
+	ret, err := c.bind(res, name, def, overrides)
 	if err != nil {
 		return nil, err
 	}
@@ -200,15 +873,51 @@ func (c *Container) create(name Identity) (*reflect.Value, error) {
 	return ret, nil
 }
 
-// Create to create a new resource from the builder definition
-func (c *Container) Create(name Identity) (interface{}, error) {
+// With replaces the cached instance of the identity with value while fn
+// runs, and restores the previous instance afterwards even if fn panics.
+// If there was no cached instance, the value is cleared afterwards. The
+// targets of BindField are assigned the value and then the previous
+// instance, if any.
+func (c *Container) With(name Identity, value interface{}, fn func()) {
 	c.Lock()
-	defer c.Unlock()
+	previous, existed := c.store[name]
+	c.store[name] = value
+	c.cached.Store(name, cachedInstance{obj: value, def: c.defs[name]})
+	c.rebind(name, value)
+	c.Unlock()
 
-	ret, err := c.create(name)
+	defer func() {
+		c.Lock()
+		defer c.Unlock()
+
+		if existed {
+			c.store[name] = previous
+			c.cached.Store(name, cachedInstance{obj: previous, def: c.defs[name]})
+			c.rebind(name, previous)
+		} else {
+			delete(c.store, name)
+			c.cached.Delete(name)
+		}
+	}()
+
+	fn()
+}
+
+// Create to create a new resource from the builder definition. In a
+// container created WithExplicitBuild, it builds and stores the singleton
+// instead.
+func (c *Container) Create(name Identity) (interface{}, error) {
+	// reading explicitBuild is safe since it's only set by the options
+	if c.explicitBuild {
+		return c.build(name)
+	}
+
+	res := newResolution(context.Background())
+	ret, err := c.create(res, name)
 	if err != nil {
 		return nil, err
 	}
+	c.track(res)
 
 	return ret.Interface(), nil
 }
@@ -219,43 +928,165 @@ func (c *Container) Create(name Identity) (interface{}, error) {
 // be assigned.
 func (c *Container) Assign(value interface{}, ids ...Identity) error {
 	var result interface{}
-	var err error
 
-	valueType := reflect.TypeOf(value)
-	if valueType == nil {
-		return errors.New("input value should not be nil")
+	target, err := settable(value)
+	if err != nil {
+		return err
 	}
 
-	et := valueType.Elem()
+	var name Identity
 	if len(ids) > 0 {
-		if result, err = c.Get(ids[0]); err != nil {
+		name = ids[0]
+		if result, err = c.Get(name); err != nil {
 			return err
 		}
-	} else {
-		if result, err = c.GetByType(et); err != nil {
+	} else if result, err = c.GetByType(target.Type()); err != nil {
+		// a registered instance which fails to build isn't replaced
+		if _, unregistered := err.(unregisteredTypeError); !unregistered {
+			return err
+		}
+
+		converted, convertible, cerr := c.fallback(newResolution(context.Background()), target.Type())
+		if !convertible {
 			return err
 		}
+		if cerr != nil {
+			return cerr
+		}
+		target.Set(converted)
+		return nil
+	}
+
+	return set(name, target, result)
+}
+
+// GetTyped gets the instance by the identity and assigns it to the target
+// which should be a pointer. Unlike Get, it returns an error if the
+// instance isn't assignable to the type target points to.
+func (c *Container) GetTyped(name Identity, target interface{}) error {
+	value, err := settable(target)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.Get(name)
+	if err != nil {
+		return err
+	}
+
+	return set(name, value, result)
+}
+
+// GetAs gets the instance by the identity and assigns it to the first
+// target it's assignable to, e.g. to adapt plugins which build different
+// types. It returns the index of the assigned target, or -1 and an error
+// if the instance isn't assignable to any of them.
+func (c *Container) GetAs(name Identity, targets ...interface{}) (int, error) {
+	values := make([]reflect.Value, 0, len(targets))
+	for _, target := range targets {
+		value, err := settable(target)
+		if err != nil {
+			return -1, err
+		}
+		values = append(values, value)
+	}
+
+	result, err := c.Get(name)
+	if err != nil {
+		return -1, err
+	}
+
+	for i, value := range values {
+		if err := set(name, value, result); err == nil {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("instance of %s is a %T which is not assignable to any target", name, result)
+}
+
+// settable returns the value which the pointer points to
+func settable(value interface{}) (reflect.Value, error) {
+	valueType := reflect.TypeOf(value)
+	if valueType == nil {
+		return reflect.Value{}, errors.New("input value should not be nil")
+	}
+
+	if valueType.Kind() != reflect.Ptr {
+		return reflect.Value{}, fmt.Errorf("input value should be a pointer instead of %s", valueType)
+	}
+
+	target := reflect.ValueOf(value)
+	if target.IsNil() {
+		return reflect.Value{}, fmt.Errorf("input value should not be a nil %s", valueType)
+	}
+
+	target = target.Elem()
+	if !target.CanSet() {
+		return reflect.Value{}, fmt.Errorf("input value of %s can't be set", valueType)
+	}
+
+	return target, nil
+}
+
+// TypeMismatchError is returned when the instance of the identity can't
+// be assigned to the target
+type TypeMismatchError struct {
+	Name     Identity     // Name is empty if the instance was resolved by the type
+	Expected reflect.Type // Expected is the type of the target
+	Actual   reflect.Type // Actual is the type of the instance
+}
+
+// Error returns the error message
+func (t TypeMismatchError) Error() string {
+	if t.Name == "" {
+		return fmt.Sprintf("instance of %s is not assignable to %s", t.Actual, t.Expected)
 	}
 
-	reflect.ValueOf(value).Elem().Set(reflect.ValueOf(result))
+	return fmt.Sprintf("instance of %s is not assignable to %s: %s", t.Name, t.Expected, t.Actual)
+}
+
+// set assigns the result of the identity to the target if the type is
+// assignable
+func set(name Identity, target reflect.Value, result interface{}) error {
+	if result == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	if resultType := reflect.TypeOf(result); !resultType.AssignableTo(target.Type()) {
+		return TypeMismatchError{Name: name, Expected: target.Type(), Actual: resultType}
+	}
+
+	target.Set(reflect.ValueOf(result))
 
 	return nil
 }
 
-func maybeError(ret []reflect.Value) error {
-	if len(ret) == 0 {
+// errorType is the reflect type of the error interface
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// maybeError returns the error from the last returned value if the
+// declared type of the last return of the function implements error
+func maybeError(ftype reflect.Type, ret []reflect.Value) error {
+	if len(ret) == 0 || !ftype.Out(len(ret)-1).Implements(errorType) {
 		return nil
 	}
 
 	// check whether there is an error or not.
-	lastRet := ret[len(ret)-1]
+	return errorOf(ret[len(ret)-1])
+}
 
-	err, ok := lastRet.Interface().(error)
-	if ok {
-		return err
+// errorOf returns the error held by the value whose type implements error
+func errorOf(value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+		if value.IsNil() {
+			return nil
+		}
 	}
 
-	return nil
+	return value.Interface().(error)
 }
 
 // checkCallee is an helper to check the basic required function signature
@@ -271,26 +1102,136 @@ func checkCallee(ftype reflect.Type) error {
 	return nil
 }
 
-// Invoke makes the input function to be called with args provided from the container
+// warnVariadic warns that the variadic args of the function are dropped,
+// the container has nothing to pass to them
+func (c *Container) warnVariadic(ftype reflect.Type) {
+	if ftype.IsVariadic() {
+		c.logf("%s is invoked without its variadic args", ftype)
+	}
+}
+
+// Invoke makes the input function to be called with args provided from the container.
+// The ids are matched to the args by position, an arg without an id (or
+// with an empty one) is resolved by its type. Args of the same type, e.g.
+// values registered by RegisterValue, need explicit ids to be told apart.
+// The order in which an arg is resolved by its type is documented by
+// buildParams. A variadic function is called without its variadic args
+// and a warning is logged, see SetLogger.
 func (c *Container) Invoke(function interface{}, ids ...Identity) error {
 	ftype := reflect.TypeOf(function)
 
 	if err := checkCallee(ftype); err != nil {
 		return err
 	}
+	c.warnVariadic(ftype)
 
 	// how to collect the args
-	args, err := buildParams(ftype, c, ids...)
+	args, err := buildParams(ftype, c, newResolution(context.Background()), nil, ids...)
+	if err != nil {
+		return err
+	}
+
+	return maybeError(ftype, invoker(reflect.ValueOf(function), args))
+}
+
+// InvokeOverride works like Invoke but the args whose type is in the
+// overrides are taken from the overrides instead of the container. It's
+// handy for replacing a dependency with a fake in a test without mutating
+// the container.
+func (c *Container) InvokeOverride(function interface{}, overrides map[reflect.Type]interface{}, ids ...Identity) error {
+	ftype := reflect.TypeOf(function)
+
+	if err := checkCallee(ftype); err != nil {
+		return err
+	}
+	c.warnVariadic(ftype)
+
+	args, err := buildParams(ftype, c, newResolution(context.Background()), overrides, ids...)
 	if err != nil {
 		return err
 	}
 
-	return maybeError(invoker(reflect.ValueOf(function), args))
+	return maybeError(ftype, invoker(reflect.ValueOf(function), args))
+}
+
+// InvokeResults works like Invoke but returns every value the function
+// returns as well, including the error if the last return is one.
+func (c *Container) InvokeResults(function interface{}, ids ...Identity) ([]interface{}, error) {
+	ftype := reflect.TypeOf(function)
+
+	if err := checkCallee(ftype); err != nil {
+		return nil, err
+	}
+	c.warnVariadic(ftype)
+
+	args, err := buildParams(ftype, c, newResolution(context.Background()), nil, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := invoker(reflect.ValueOf(function), args)
+	results := make([]interface{}, 0, len(ret))
+	for _, r := range ret {
+		results = append(results, r.Interface())
+	}
+
+	return results, maybeError(ftype, ret)
+}
+
+// ResolveArgs returns the args which Invoke would pass to the function
+// without calling it, e.g. to log them or to call the function later. The
+// ids are matched to the args like Invoke.
+func (c *Container) ResolveArgs(function interface{}, ids ...Identity) ([]interface{}, error) {
+	ftype := reflect.TypeOf(function)
+
+	if err := checkCallee(ftype); err != nil {
+		return nil, err
+	}
+
+	args, err := buildParams(ftype, c, newResolution(context.Background()), nil, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		results = append(results, arg.Interface())
+	}
+
+	return results, nil
 }
 
-// grabe the args from the fn and build them from the container
-func buildParams(fn reflect.Type, c *Container, ids ...Identity) ([]reflect.Value, error) {
-	args := []reflect.Value{}
+// InvokeMethod calls the method of the receiver resolved by receiverType,
+// e.g. (*Service).Handle, with args provided from the container. The ids
+// are matched to the args of the method like Invoke, the receiver isn't
+// counted.
+func (c *Container) InvokeMethod(receiverType reflect.Type, methodName string, ids ...Identity) error {
+	receiver, err := c.GetByType(receiverType)
+	if err != nil {
+		return err
+	}
+
+	method := reflect.ValueOf(receiver).MethodByName(methodName)
+	if !method.IsValid() {
+		return fmt.Errorf("%s has no method %s", receiverType, methodName)
+	}
+
+	return c.Invoke(method.Interface(), ids...)
+}
+
+// noArgs is shared by every call of a function which takes no args. It's
+// never modified so it's safe to be reused.
+var noArgs = []reflect.Value{}
+
+// grabe the args from the fn and build them from the container. A param
+// given an identity is resolved by it, the others are resolved by their
+// types in this order:
+//
+//  1. the identities registered with the type, see GetByType
+//  2. the identities registered with a type implementing the interface
+//  3. the instance of *T for T and the instance of T for *T, see fallback
+//  4. the converters to the type, see RegisterConverter
+func buildParams(fn reflect.Type, c *Container, res *resolution, overrides map[reflect.Type]interface{}, ids ...Identity) ([]reflect.Value, error) {
 	var arg interface{}
 	var err error
 	numArgs := fn.NumIn()
@@ -300,28 +1241,154 @@ func buildParams(fn reflect.Type, c *Container, ids ...Identity) ([]reflect.Valu
 		numArgs--
 	}
 
-	for i := 0; i < numArgs; i++ {
+	if numArgs == 0 {
+		return noArgs, nil
+	}
+
+	cached, gen := c.cachedParams(fn)
+	var resolved []Identity
+
+	args := make([]reflect.Value, 0, numArgs)
+	args = append(args, res.literals...)
+
+	for i := len(res.literals); i < numArgs; i++ {
 		argType := fn.In(i)
-		// try to get the arg from the container with argType?
-		if len(ids) > 0 {
-			if arg, err = c.Get(ids[i]); err != nil {
+
+		// a Lazy parameter is bound to the container instead of being built,
+		// a dry run still tells the identity it would resolve
+		if elem, isLazy := lazyElem(argType); isLazy && res.dryRun {
+			id, err := c.paramIdentity(i, elem, ids)
+			if err != nil {
 				return nil, err
 			}
-		} else {
+			res.resolved(id)
+		}
+
+		if reflect.PtrTo(argType).Implements(lazyBinderType) {
+			lazy := reflect.New(argType)
+			lazy.Interface().(lazyBinder).bindContainer(c)
+			args = append(args, lazy.Elem())
+			continue
+		}
 
-			if arg, err = c.GetByType(argType); err != nil {
+		if override, exists := overrides[argType]; exists {
+			if override == nil {
+				args = append(args, reflect.Zero(argType))
+			} else {
+				args = append(args, reflect.ValueOf(override))
+			}
+			continue
+		}
+
+		if isDeps(argType) {
+			deps, err := c.buildDeps(res, argType)
+			if err != nil {
 				return nil, err
 			}
+			args = append(args, deps)
+			continue
+		}
+
+		// try to get the arg from the container with argType?
+		var id Identity
+		byType := i >= len(ids) || ids[i] == ""
+		if byType && i < len(cached) && cached[i] != "" {
+			id = cached[i]
+		} else {
+			if id, err = c.paramIdentity(i, argType, ids); err != nil {
+				if _, unregistered := err.(unregisteredTypeError); !byType || !unregistered {
+					return nil, err
+				}
+
+				// the instance may be derived from another type
+				converted, convertible, cerr := c.fallback(res, argType)
+				if !convertible {
+					return nil, err
+				}
+				if cerr != nil {
+					return nil, cerr
+				}
+				args = append(args, converted)
+				continue
+			}
+
+			if byType && cached == nil {
+				if resolved == nil {
+					resolved = make([]Identity, numArgs)
+				}
+				resolved[i] = id
+			}
+		}
 
+		if arg, err = c.get(res, id); err != nil {
+			return nil, err
 		}
+		res.resolved(id)
 
+		if arg == nil {
+			args = append(args, reflect.Zero(argType))
+			continue
+		}
+
+		// an identity given by the caller may not build the type of the param
 		what := reflect.ValueOf(arg)
+		if !what.Type().AssignableTo(argType) {
+			return nil, TypeMismatchError{Name: id, Expected: argType, Actual: what.Type()}
+		}
 		args = append(args, what)
 	}
 
+	if resolved != nil {
+		c.cacheParams(fn, resolved, gen)
+	}
+
 	return args, nil
 }
 
+// paramIdentity chooses the identity which satisfies the i-th param. The
+// identities given by the caller are positional, a missing or empty one
+// is resolved by the type.
+func (c *Container) paramIdentity(i int, argType reflect.Type, ids []Identity) (Identity, error) {
+	if i < len(ids) && ids[i] != "" {
+		return ids[i], nil
+	}
+
+	id, exists, err := c.lookup(argType)
+	if err != nil {
+		return "", err
+	}
+
+	if !exists {
+		return "", unregisteredTypeError{t: argType}
+	}
+
+	return id, nil
+}
+
+// WouldResolve returns the identities which would be resolved for the
+// function by Invoke without building anything. It follows the order of
+// buildParams, so a param derived from *T or T, or converted from another
+// type, is reported with the identity it's resolved from, and a Deps param
+// with the identities of its fields. A Lazy[T] parameter is reported with
+// the identity of T.
+func (c *Container) WouldResolve(function interface{}, ids ...Identity) ([]Identity, error) {
+	ftype := reflect.TypeOf(function)
+
+	if err := checkCallee(ftype); err != nil {
+		return nil, err
+	}
+
+	res := newResolution(context.Background())
+	res.dryRun = true
+	res.trackDeps = true
+
+	if _, err := buildParams(ftype, c, res, nil, ids...); err != nil {
+		return nil, err
+	}
+
+	return append([]Identity{}, res.deps...), nil
+}
+
 func invoker(fn reflect.Value, args []reflect.Value) []reflect.Value {
 	return fn.Call(args)
 }
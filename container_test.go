@@ -1,9 +1,13 @@
 package objectcommander
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewContainer(t *testing.T) {
@@ -90,6 +94,98 @@ func TestInvokeWithSpecifiedIdentity(t *testing.T) {
 
 }
 
+type testNotifier interface {
+	Notify(msg string) string
+}
+
+type emailNotifier struct{ from string }
+
+func (e *emailNotifier) Notify(msg string) string { return "email: " + msg }
+
+type smsNotifier struct{ number string }
+
+func (s *smsNotifier) Notify(msg string) string { return "sms: " + msg }
+
+func TestInvokeInterfaceByIdentity(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("email"), func() testNotifier { return &emailNotifier{from: "noreply"} })
+	c.Register(Identity("sms"), func() testNotifier { return &smsNotifier{number: "911"} })
+	c.Register(Identity("port"), func() int { return 80 })
+
+	var sent string
+	notify := func(n testNotifier) {
+		sent = n.Notify("hi")
+	}
+
+	if err := c.Invoke(notify); err != nil || sent != "email: hi" {
+		t.Errorf("the first binding should be resolved by the type: %s %v", sent, err)
+	}
+
+	if err := c.Invoke(notify, Identity("sms")); err != nil || sent != "sms: hi" {
+		t.Errorf("the identity should select the sms binding: %s %v", sent, err)
+	}
+
+	err := c.Invoke(notify, Identity("port"))
+	if _, ok := err.(TypeMismatchError); !ok {
+		t.Errorf("an identity of another type should be rejected: %v", err)
+	}
+}
+
+type testReader interface {
+	Read() string
+}
+
+type testWriter interface {
+	Write(s string)
+}
+
+type testReadWriter interface {
+	testReader
+	testWriter
+}
+
+type memoryFile struct{ content string }
+
+func (m *memoryFile) Read() string   { return m.content }
+func (m *memoryFile) Write(s string) { m.content += s }
+
+func TestResolveEmbeddedInterface(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("file"), func() testReadWriter { return &memoryFile{content: "hello"} })
+
+	var read string
+	if err := c.Invoke(func(r testReader) { read = r.Read() }); err != nil || read != "hello" {
+		t.Errorf("a ReadWriter should resolve a Reader: %s %v", read, err)
+	}
+
+	var w testWriter
+	if err := c.Assign(&w); err != nil || w != c.MustGet(Identity("file")) {
+		t.Errorf("a ReadWriter should resolve a Writer: %v", err)
+	}
+
+	c.Register(Identity("reader"), func() testReader { return &memoryFile{content: "exact"} })
+	if err := c.Invoke(func(r testReader) { read = r.Read() }); err != nil || read != "exact" {
+		t.Errorf("the exact type should win over the implementing ones: %s %v", read, err)
+	}
+}
+
+func TestResolveEmptyInterface(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("file"), func() *memoryFile { return &memoryFile{content: "hello"} })
+
+	if err := c.Invoke(func(v interface{}) {}); err == nil {
+		t.Error("an interface{} param should not be resolved by any instance")
+	}
+
+	var read string
+	if err := c.Invoke(func(rw testReadWriter) { read = rw.Read() }); err != nil || read != "hello" {
+		t.Errorf("a concrete type should still resolve the interfaces it implements: %s %v", read, err)
+	}
+}
+
 func TestRegister(t *testing.T) {
 
 	c := NewContainer()
@@ -125,6 +221,16 @@ func TestRegister(t *testing.T) {
 		t.Error("failed to register config in container")
 	}
 
+	err = c.Register(configName, configBuild)
+	var registered AlreadyRegisteredError
+	if !errors.As(fmt.Errorf("boot: %w", err), &registered) || registered.Name != configName {
+		t.Error("failed to read the identity from the error")
+	}
+
+	if err.Error() != "config was already registered" {
+		t.Errorf("unexpected error message: %s", err)
+	}
+
 	if loadedDefs != "" {
 		t.Error("register should be a lazy action")
 	}
@@ -276,3 +382,932 @@ func TestUnregistered(t *testing.T) {
 	}
 
 }
+
+func TestGetOr(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("cache"), func() string {
+		return "redis"
+	})
+
+	if c.GetOr(Identity("cache"), "memory").(string) != "redis" {
+		t.Error("should get the registered instance")
+	}
+
+	if c.GetOr(Identity("queue"), "memory").(string) != "memory" {
+		t.Error("should get the fallback for an unregistered instance")
+	}
+}
+
+func TestInvokeResults(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("port"), func() int { return 8080 })
+
+	results, err := c.InvokeResults(func(port int) (int, string, error) {
+		return port + 1, "ok", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 || results[0] != 8081 || results[1] != "ok" || results[2] != nil {
+		t.Errorf("every result should be returned: %v", results)
+	}
+
+	results, err = c.InvokeResults(func(port int) (int, error) {
+		return port, errors.New("closed")
+	})
+	if err == nil || len(results) != 2 || results[0] != 8080 {
+		t.Errorf("the error should be extracted along with the results: %v %v", results, err)
+	}
+}
+
+func TestResolveArgs(t *testing.T) {
+
+	c := NewContainer()
+	c.RegisterValue(Identity("name"), "api")
+	c.RegisterValue(Identity("port"), 8080)
+
+	called := false
+	args, err := c.ResolveArgs(func(name string, port int) { called = true })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(args, []interface{}{"api", 8080}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+
+	if called {
+		t.Error("the function should not be called")
+	}
+
+	if _, err := c.ResolveArgs(func(float64) {}); err == nil {
+		t.Error("should fail for an unresolvable arg")
+	}
+}
+
+func TestFuncSingleton(t *testing.T) {
+
+	c := NewContainer()
+	c.RegisterValue(Identity("factor"), 3)
+	c.Register(Identity("multiply"), func(factor int) func(int) int {
+		return func(n int) int { return n * factor }
+	})
+
+	multiply := c.MustGet(Identity("multiply")).(func(int) int)
+	if multiply(2) != 6 {
+		t.Error("the resolved function should be callable")
+	}
+
+	byType, err := c.GetByType(reflect.TypeOf(multiply))
+	if err != nil || byType.(func(int) int)(3) != 9 {
+		t.Errorf("the function should be resolved by its type: %v", err)
+	}
+
+	var assigned func(int) int
+	if err := c.Assign(&assigned); err != nil || assigned(4) != 12 {
+		t.Errorf("the function should be assigned: %v", err)
+	}
+
+	if err := c.Invoke(func(fn func(int) int) {
+		if fn(5) != 15 {
+			t.Error("the function param should be resolved")
+		}
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPeek(t *testing.T) {
+
+	builds := 0
+	c := NewContainer()
+	c.Register(Identity("pool"), func() *testClient {
+		builds++
+		return &testClient{name: "pool"}
+	})
+
+	if obj, built := c.Peek(Identity("pool")); built || obj != nil {
+		t.Error("an instance which isn't built should not be peeked")
+	}
+
+	if builds != 0 {
+		t.Fatal("Peek should never invoke the builder")
+	}
+
+	pool := c.MustGet(Identity("pool"))
+	if obj, built := c.Peek(Identity("pool")); !built || obj != pool || builds != 1 {
+		t.Error("Peek should return the built instance")
+	}
+
+	if _, built := c.Peek(Identity("nop")); built {
+		t.Error("an unregistered identity should not be peeked")
+	}
+}
+
+func TestTryGet(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("cache"), func() string { return "redis" })
+	c.Register(Identity("queue"), func() (int, error) { return 0, errors.New("unreachable") })
+
+	if cache, ok := c.TryGet(Identity("cache")); !ok || cache.(string) != "redis" {
+		t.Error("should get the instance")
+	}
+
+	if queue, ok := c.TryGet(Identity("queue")); ok || queue != nil {
+		t.Error("a failing builder should yield (nil, false)")
+	}
+
+	if _, ok := c.TryGet(Identity("nop")); ok {
+		t.Error("an unregistered identity should yield false")
+	}
+}
+
+func TestGetWhere(t *testing.T) {
+
+	type DSN struct{ Env string }
+
+	c := NewContainer()
+	for _, env := range []string{"dev", "staging", "prod"} {
+		env := env
+		c.Register(Identity("dsn-"+env), func() *DSN { return &DSN{Env: env} })
+	}
+
+	dsnType := reflect.TypeOf(&DSN{})
+	result, err := c.GetWhere(dsnType, func(name Identity) bool {
+		return strings.HasSuffix(string(name), "staging")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.(*DSN).Env != "staging" {
+		t.Errorf("should pick the matching identity: %+v", result)
+	}
+
+	if _, err := c.GetWhere(dsnType, func(name Identity) bool { return false }); err == nil {
+		t.Error("should fail if nothing matches")
+	}
+}
+
+func TestInvokeParamCache(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("name"), func() string { return "api" })
+
+	var got string
+	fn := func(name string) { got = name }
+
+	c.Invoke(fn)
+	if got != "api" {
+		t.Fatalf("unexpected arg: %s", got)
+	}
+
+	c.Register(Identity("preferred"), func() string { return "web" }, WithPriority(1))
+	c.Invoke(fn)
+	if got != "web" {
+		t.Errorf("the cached identity should be dropped on register: %s", got)
+	}
+
+	c.Unregister(Identity("preferred"))
+	c.Invoke(fn)
+	if got != "api" {
+		t.Errorf("the cached identity should be dropped on unregister: %s", got)
+	}
+
+	c.Invoke(fn, Identity("name"))
+	c.Override(Identity("name"), func() string { return "override" })
+	c.Invoke(fn)
+	if got != "override" {
+		t.Errorf("the overridden builder should be used: %s", got)
+	}
+}
+
+func BenchmarkCreateWithoutArgs(b *testing.B) {
+
+	c := NewContainer()
+	c.Register(Identity("config"), func() string {
+		return "config"
+	})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Create(Identity("config"))
+	}
+}
+
+func BenchmarkInvokeWithArgs(b *testing.B) {
+
+	c := NewContainer()
+	c.Register(Identity("config"), func() string {
+		return "config"
+	})
+
+	c.Register(Identity("port"), func() int {
+		return 8080
+	})
+
+	fn := func(config string, port int) {}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Invoke(fn)
+	}
+}
+
+type testLogger interface {
+	Log(msg string)
+}
+
+type stdoutLogger struct{}
+
+func (stdoutLogger) Log(msg string) { fmt.Println(msg) }
+
+type bufferLogger struct{ buf *bytes.Buffer }
+
+func (l bufferLogger) Log(msg string) { l.buf.WriteString(msg) }
+
+func TestInvokeOverride(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("logger"), func() testLogger {
+		return stdoutLogger{}
+	})
+	c.Register(Identity("name"), func() string {
+		return "alice"
+	})
+
+	buf := &bytes.Buffer{}
+	overrides := map[reflect.Type]interface{}{
+		reflect.TypeOf((*testLogger)(nil)).Elem(): bufferLogger{buf: buf},
+	}
+
+	err := c.InvokeOverride(func(l testLogger, name string) {
+		l.Log("hello " + name)
+	}, overrides)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if buf.String() != "hello alice" {
+		t.Errorf("the logger was not overridden: %q", buf.String())
+	}
+}
+
+func TestMustGetInsideBuilder(t *testing.T) {
+
+	c := NewContainer()
+	type DB struct{ DSN string }
+
+	c.Register(Identity("dsn"), func() string {
+		return "postgres://"
+	})
+	c.Register(Identity("db"), func() DB {
+		// resolving a sibling dependency inside the builder body
+		return DB{DSN: c.MustGet(Identity("dsn")).(string)}
+	})
+
+	done := make(chan interface{})
+	go func() {
+		db, _ := c.Get(Identity("db"))
+		done <- db
+	}()
+
+	select {
+	case db := <-done:
+		if db.(DB).DSN != "postgres://" {
+			t.Error("get the wrong dependency")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("nested resolution deadlocked")
+	}
+
+	if len(c.store) != 2 {
+		t.Error("both instances should be cached")
+	}
+
+	// Create resolves its dependencies from the container as well
+	if _, err := c.Create(Identity("db")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateBuilder(t *testing.T) {
+
+	if err := ValidateBuilder(func() string { return "" }); err != nil {
+		t.Error(err)
+	}
+
+	invalids := []struct {
+		build Builder
+		msg   string
+	}{
+		{nil, "can't invoke nil type"},
+		{"builder", "can't invoke non-function"},
+		{func() {}, "expect builder function returns one value"},
+		{func() (string, int) { return "", 0 }, "expect builder function returns one value"},
+	}
+
+	for _, invalid := range invalids {
+		err := ValidateBuilder(invalid.build)
+		if err == nil || !strings.Contains(err.Error(), invalid.msg) {
+			t.Errorf("expected %q but get %v", invalid.msg, err)
+		}
+	}
+
+	c := NewContainer()
+	if err := c.Register(Identity("invalid"), "builder"); err == nil {
+		t.Error("register should reject an invalid builder")
+	}
+}
+
+func TestNewContainerWith(t *testing.T) {
+
+	type DB struct{ DSN string }
+
+	c, err := NewContainerWith(map[Identity]Builder{
+		Identity("dsn"): func() string { return "postgres://" },
+		Identity("db"):  func(dsn string) DB { return DB{DSN: dsn} },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.MustGet(Identity("db")).(DB).DSN != "postgres://" {
+		t.Error("failed to resolve the pre-registered definitions")
+	}
+
+	_, err = NewContainerWith(map[Identity]Builder{
+		Identity("invalid"): "builder",
+	})
+	if err == nil {
+		t.Error("should fail on an invalid definition")
+	}
+}
+
+func TestRegisterIf(t *testing.T) {
+
+	c := NewContainer()
+	build := func() string { return "metrics" }
+
+	if err := c.RegisterIf(false, Identity("metrics"), build); err != nil {
+		t.Error("a false condition should not be an error")
+	}
+
+	if _, err := c.Get(Identity("metrics")); err == nil {
+		t.Error("metrics should not be registered")
+	}
+
+	if err := c.RegisterIfFunc(func() bool { return true }, Identity("metrics"), build); err != nil {
+		t.Error(err)
+	}
+
+	if c.MustGet(Identity("metrics")).(string) != "metrics" {
+		t.Error("metrics should be registered")
+	}
+}
+
+func TestForEach(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("name"), func() string { return "name" })
+	c.Register(Identity("port"), func() int { return 80 })
+	c.Register(Identity("debug"), func() bool { return true })
+
+	visited := map[Identity]reflect.Type{}
+	c.ForEach(func(name Identity, t reflect.Type) bool {
+		visited[name] = t
+		return true
+	})
+
+	if len(visited) != 3 || visited[Identity("port")] != reflect.TypeOf(0) {
+		t.Errorf("failed to visit every definition: %v", visited)
+	}
+
+	count := 0
+	c.ForEach(func(name Identity, t reflect.Type) bool {
+		count++
+		return false
+	})
+
+	if count != 1 {
+		t.Error("should stop once fn returns false")
+	}
+}
+
+func TestFlushALLAndResetAll(t *testing.T) {
+
+	type Port int
+
+	setup := func(c *Container) {
+		c.Register(Identity("port"), func() int { return 80 })
+		c.Register(Identity("alt"), func() int { return 8080 })
+	}
+
+	hooked := 0
+	c := NewContainer()
+	logger := &recordLogger{}
+	c.SetLogger(logger)
+	c.BeforeBuild(func(name Identity, deps []Identity) { hooked++ })
+	c.RegisterConverter(reflect.TypeOf(0), reflect.TypeOf(Port(0)), func(v interface{}) interface{} {
+		return Port(v.(int))
+	})
+	c.SetDefaultIdentity(reflect.TypeOf(0), Identity("alt"))
+	setup(c)
+
+	var bound int
+	c.BindField(&bound, Identity("port"))
+
+	c.FlushALL()
+	if _, err := c.Get(Identity("port")); err == nil {
+		t.Fatal("FlushALL should clear the definitions")
+	}
+
+	if len(c.bindings) != 0 {
+		t.Error("FlushALL should drop the bindings of the cleared definitions")
+	}
+
+	setup(c)
+	hooked = 0
+	var port Port
+	if err := c.Assign(&port); err != nil || port != 8080 {
+		t.Errorf("FlushALL should keep the converters and the default identities: %d %v", port, err)
+	}
+
+	if hooked == 0 {
+		t.Error("FlushALL should keep the hooks")
+	}
+
+	c.SetResolutionStrategy(func(t reflect.Type, candidates []Identity) (Identity, error) {
+		return candidates[len(candidates)-1], nil
+	})
+	c.ResetAll()
+	setup(c)
+	hooked = 0
+
+	if err := c.Assign(&port); err == nil {
+		t.Error("ResetAll should clear the converters")
+	}
+
+	var plain int
+	if err := c.Assign(&plain); err != nil || plain != 80 {
+		t.Errorf("ResetAll should clear the default identities and the strategy: %d %v", plain, err)
+	}
+
+	if hooked != 0 {
+		t.Error("ResetAll should clear the hooks")
+	}
+
+	logger.messages = nil
+	c.Register(Identity("other port"), func() int { return 0 })
+	if len(logger.messages) == 0 {
+		t.Error("ResetAll should keep the logger")
+	}
+}
+
+func TestLen(t *testing.T) {
+
+	c := NewContainer()
+	if defs, instances := c.Len(); defs != 0 || instances != 0 {
+		t.Errorf("an empty container should be empty: %d %d", defs, instances)
+	}
+
+	c.Register(Identity("port"), func() int { return 80 })
+	c.Register(Identity("host"), func() string { return "localhost" })
+	if defs, instances := c.Len(); defs != 2 || instances != 0 {
+		t.Errorf("nothing should be built yet: %d %d", defs, instances)
+	}
+
+	c.MustGet(Identity("port"))
+	c.MustGet(Identity("port"))
+	if defs, instances := c.Len(); defs != 2 || instances != 1 {
+		t.Errorf("the built singleton should be counted once: %d %d", defs, instances)
+	}
+
+	c.Unregister(Identity("port"))
+	if defs, instances := c.Len(); defs != 1 || instances != 0 {
+		t.Errorf("the unregistered definition and its instance should not be counted: %d %d", defs, instances)
+	}
+}
+
+func TestUnregisterKeepsOtherIdentities(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("alice"), func() string { return "alice" })
+	c.Register(Identity("bob"), func() string { return "bob" })
+	c.Unregister(Identity("alice"))
+
+	if ids := c.typeToIdentity[reflect.TypeOf("")]; len(ids) != 1 || ids[0] != Identity("bob") {
+		t.Errorf("unexpected type index after unregistering: %v", ids)
+	}
+}
+
+func TestAssignUnsettableTarget(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("port"), func() int { return 80 })
+
+	var port int
+	var nilPort *int
+
+	targets := []struct {
+		value interface{}
+		msg   string
+	}{
+		{nil, "should not be nil"},
+		{port, "should be a pointer"},
+		{nilPort, "should not be a nil"},
+	}
+
+	for _, target := range targets {
+		err := c.Assign(target.value)
+		if err == nil || !strings.Contains(err.Error(), target.msg) {
+			t.Errorf("expected %q but get %v", target.msg, err)
+		}
+	}
+
+	if err := c.Assign(&port); err != nil || port != 80 {
+		t.Error("failed to assign a settable target")
+	}
+}
+
+func TestWith(t *testing.T) {
+
+	type Clock struct{ Now string }
+
+	c := NewContainer()
+	c.Register(Identity("clock"), func() Clock { return Clock{Now: "real"} })
+	c.MustGet(Identity("clock"))
+
+	c.With(Identity("clock"), Clock{Now: "fake"}, func() {
+		if c.MustGet(Identity("clock")).(Clock).Now != "fake" {
+			t.Error("the instance should be replaced inside the scope")
+		}
+	})
+
+	if c.MustGet(Identity("clock")).(Clock).Now != "real" {
+		t.Error("the original instance should be restored")
+	}
+
+	func() {
+		defer func() { recover() }()
+		c.With(Identity("clock"), Clock{Now: "fake"}, func() {
+			panic("boom")
+		})
+	}()
+
+	if c.MustGet(Identity("clock")).(Clock).Now != "real" {
+		t.Error("the original instance should be restored after a panic")
+	}
+
+	c.With(Identity("timezone"), "UTC", func() {
+		if c.MustGet(Identity("timezone")).(string) != "UTC" {
+			t.Error("the value should be available inside the scope")
+		}
+	})
+
+	if _, err := c.Get(Identity("timezone")); err == nil {
+		t.Error("the value should be cleared if there was no instance")
+	}
+}
+
+func TestGetTyped(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("name"), func() string { return "api" })
+
+	var name string
+	if err := c.GetTyped(Identity("name"), &name); err != nil || name != "api" {
+		t.Errorf("failed to get the typed instance: %v", err)
+	}
+
+	var port int
+	err := c.GetTyped(Identity("name"), &port)
+	if err == nil || !strings.Contains(err.Error(), "not assignable") {
+		t.Errorf("should detect the mismatched type: %v", err)
+	}
+}
+
+func TestGetAs(t *testing.T) {
+
+	type FileStore struct{ Dir string }
+
+	c := NewContainer()
+	c.Register(Identity("store"), func() *FileStore { return &FileStore{Dir: "/tmp"} })
+
+	var (
+		client testClient
+		store  *FileStore
+		name   string
+	)
+
+	matched, err := c.GetAs(Identity("store"), &client, &store, &name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if matched != 1 || store == nil || store.Dir != "/tmp" {
+		t.Errorf("should assign the second target: %d %+v", matched, store)
+	}
+
+	if matched, err := c.GetAs(Identity("store"), &client, &name); err == nil || matched != -1 {
+		t.Error("should fail if no target is assignable")
+	}
+}
+
+func TestAssignTypeMismatch(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("foo"), func() string { return "bar" })
+
+	var port int
+	err := c.Assign(&port, Identity("foo"))
+
+	var mismatch TypeMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("should return a TypeMismatchError: %v", err)
+	}
+
+	if mismatch.Name != Identity("foo") || mismatch.Expected != reflect.TypeOf(0) || mismatch.Actual != reflect.TypeOf("") {
+		t.Errorf("unexpected mismatch: %+v", mismatch)
+	}
+}
+
+func TestMissingDependencyError(t *testing.T) {
+
+	type B struct{}
+	type A struct{ b B }
+
+	c := NewContainer()
+	c.Register(Identity("a"), func(b B) A { return A{b: b} })
+
+	_, err := c.Get(Identity("a"))
+	if err == nil {
+		t.Fatal("expected an error for the missing dependency")
+	}
+
+	if !strings.Contains(err.Error(), "of a") || !strings.Contains(err.Error(), reflect.TypeOf(B{}).String()) {
+		t.Errorf("the error should name both a and B: %s", err)
+	}
+}
+
+type boolError bool
+
+func (b boolError) Error() string { return "bool error" }
+
+func TestInvokeErrorDetection(t *testing.T) {
+
+	c := NewContainer()
+
+	if err := c.Invoke(func() (int, bool) { return 1, false }); err != nil {
+		t.Errorf("a bool last return should not be an error: %v", err)
+	}
+
+	// the declared type doesn't implement error even if the value does
+	if err := c.Invoke(func() interface{} { return boolError(true) }); err != nil {
+		t.Errorf("a non-error declared return should not be an error: %v", err)
+	}
+
+	if err := c.Invoke(func() error { return nil }); err != nil {
+		t.Errorf("a nil error should not be an error: %v", err)
+	}
+
+	if err := c.Invoke(func() error { return errors.New("failed") }); err == nil {
+		t.Error("failed to detect the returned error")
+	}
+
+	if err := c.Invoke(func() boolError { return boolError(true) }); err == nil {
+		t.Error("failed to detect a concrete error type")
+	}
+}
+
+func TestWouldResolve(t *testing.T) {
+
+	type Store interface{}
+
+	built := 0
+	c := NewContainer()
+	c.Register(Identity("memory"), func() Store { built++; return "memory" })
+	c.Register(Identity("postgres"), func() Store { built++; return "postgres" }, WithPriority(1))
+	c.Register(Identity("name"), func() string { built++; return "api" })
+
+	ids, err := c.WouldResolve(func(s Store, name string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ids, []Identity{"postgres", "name"}) {
+		t.Errorf("unexpected identities: %v", ids)
+	}
+
+	if built != 0 {
+		t.Error("nothing should be built")
+	}
+
+	if _, err := c.WouldResolve(func(port int) {}); err == nil {
+		t.Error("should report the unresolvable param")
+	}
+}
+
+func TestWouldResolveFallback(t *testing.T) {
+
+	type Config struct{ DSN string }
+	type Port int
+	type Params struct {
+		Deps
+		Name string
+		Port int
+	}
+
+	built := 0
+	c := NewContainer()
+	c.Register(Identity("config"), func() Config { built++; return Config{} })
+	c.Register(Identity("raw port"), func() int { built++; return 80 })
+	c.Register(Identity("name"), func() string { built++; return "api" })
+	c.RegisterConverter(reflect.TypeOf(0), reflect.TypeOf(Port(0)), func(v interface{}) interface{} {
+		return Port(v.(int))
+	})
+
+	ids, err := c.WouldResolve(func(config *Config, port Port, params Params) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ids, []Identity{"config", "raw port", "name", "raw port"}) {
+		t.Errorf("the derived, converted and Deps params should be reported: %v", ids)
+	}
+
+	if built != 0 {
+		t.Error("nothing should be built")
+	}
+}
+
+func TestRegisterValue(t *testing.T) {
+
+	c := NewContainer()
+	c.RegisterValue(Identity("maxRetries"), 3)
+	c.RegisterValue(Identity("timeout"), 30)
+	c.RegisterValue(Identity("name"), "api")
+
+	if err := c.RegisterValue(Identity("nil"), nil); err == nil {
+		t.Error("should reject a nil value")
+	}
+
+	err := c.Invoke(func(retries int, timeout int, name string) {
+		if retries != 3 || timeout != 30 || name != "api" {
+			t.Errorf("unexpected values: %d %d %s", retries, timeout, name)
+		}
+	}, Identity("maxRetries"), Identity("timeout"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	// an empty id falls back to the type
+	err = c.Invoke(func(name string, timeout int) {
+		if name != "api" || timeout != 30 {
+			t.Errorf("unexpected values: %s %d", name, timeout)
+		}
+	}, Identity(""), Identity("timeout"))
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBuilderReturnsError(t *testing.T) {
+
+	type DB struct{ DSN string }
+	broken := errors.New("connection refused")
+
+	c := NewContainer()
+	c.Register(Identity("db"), func() (*DB, error) { return &DB{DSN: "primary"}, nil })
+	c.Register(Identity("replica"), func() (error, *DB) { return nil, &DB{DSN: "replica"} })
+	c.Register(Identity("broken"), func() (error, *DB) { return broken, nil })
+
+	if c.MustGet(Identity("db")).(*DB).DSN != "primary" {
+		t.Error("failed to build a (T, error) builder")
+	}
+
+	if c.MustGet(Identity("replica")).(*DB).DSN != "replica" {
+		t.Error("failed to build an (error, T) builder")
+	}
+
+	if _, err := c.Get(Identity("broken")); !errors.Is(err, broken) {
+		t.Errorf("expected the builder error but get %v", err)
+	}
+
+	if _, exists := c.store[Identity("broken")]; exists {
+		t.Error("a failed build should not be cached")
+	}
+
+	// the type is indexed by the value instead of the error
+	if ids := c.typeToIdentity[reflect.TypeOf(&DB{})]; len(ids) != 3 {
+		t.Errorf("unexpected type index: %v", ids)
+	}
+
+	if err := c.Register(Identity("two"), func() (error, error) { return nil, nil }); err == nil {
+		t.Error("should reject two error returns")
+	}
+
+	if err := c.Register(Identity("pair"), func() (*DB, string) { return nil, "" }); err == nil {
+		t.Error("should reject two value returns")
+	}
+}
+
+type testClient struct {
+	name    string
+	timeout int
+	retries int
+}
+
+type testOption func(*testClient)
+
+func TestRegisterVariadic(t *testing.T) {
+
+	withTimeout := testOption(func(c *testClient) { c.timeout = 30 })
+	withRetries := testOption(func(c *testClient) { c.retries = 3 })
+
+	c := NewContainer()
+	c.Register(Identity("name"), func() string { return "api" })
+
+	err := c.RegisterVariadic(Identity("client"), func(name string, opts ...testOption) *testClient {
+		client := &testClient{name: name}
+		for _, opt := range opts {
+			opt(client)
+		}
+		return client
+	}, withTimeout, withRetries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := c.MustGet(Identity("client")).(*testClient)
+	if client.name != "api" || client.timeout != 30 || client.retries != 3 {
+		t.Errorf("the options were not applied: %+v", client)
+	}
+
+	err = c.RegisterVariadic(Identity("invalid"), func(opts ...testOption) *testClient { return nil }, "timeout")
+	if err == nil {
+		t.Error("should reject a variadic arg of another type")
+	}
+
+	err = c.RegisterVariadic(Identity("fixed"), func() *testClient { return nil }, withTimeout)
+	if err == nil {
+		t.Error("should reject a non-variadic builder")
+	}
+}
+
+type testService struct {
+	prefix  string
+	handled []string
+}
+
+func (s *testService) Handle(name string) {
+	s.handled = append(s.handled, s.prefix+name)
+}
+
+func TestInvokeMethod(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("service"), func() *testService { return &testService{prefix: "hello "} })
+	c.RegisterValue(Identity("name"), "world")
+
+	serviceType := reflect.TypeOf(&testService{})
+	if err := c.InvokeMethod(serviceType, "Handle"); err != nil {
+		t.Fatal(err)
+	}
+
+	service := c.MustGet(Identity("service")).(*testService)
+	if len(service.handled) != 1 || service.handled[0] != "hello world" {
+		t.Errorf("the method was not invoked with the resolved args: %v", service.handled)
+	}
+
+	if err := c.InvokeMethod(serviceType, "Missing"); err == nil {
+		t.Error("should reject a missing method")
+	}
+}
+
+func BenchmarkInvokeByType(b *testing.B) {
+
+	type A struct{}
+	type B struct{}
+	type C struct{}
+	type D struct{}
+
+	c := NewContainer()
+	c.Register(Identity("a"), func() *A { return &A{} })
+	c.Register(Identity("b"), func() *B { return &B{} })
+	c.Register(Identity("c"), func() *C { return &C{} })
+	c.Register(Identity("d"), func() *D { return &D{} })
+	c.Register(Identity("name"), func() string { return "api" }, WithPriority(1))
+	c.Register(Identity("fallback"), func() string { return "fallback" })
+
+	fn := func(*A, *B, *C, *D, string) {}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Invoke(fn)
+	}
+}
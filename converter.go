@@ -0,0 +1,142 @@
+package objectcommander
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// converter converts an instance of from to the type it's registered for
+type converter struct {
+	from reflect.Type
+	conv func(interface{}) interface{}
+}
+
+// RegisterConverter makes a param or an Assign target of type to be
+// resolved from the instance of type from when nothing is registered with
+// type to, e.g. a named type from its underlying type. It's an escape
+// hatch for the types which don't match, an interface is satisfied by the
+// instances implementing it without a converter. The converters of a type
+// are tried in the order of the registration.
+func (c *Container) RegisterConverter(from, to reflect.Type, conv func(interface{}) interface{}) error {
+	if from == nil || to == nil || conv == nil {
+		return fmt.Errorf("the types and the converter should not be nil")
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.frozen {
+		return FrozenContainerError{Op: "register a converter to " + to.String()}
+	}
+
+	if c.converters == nil {
+		c.converters = make(map[reflect.Type][]converter)
+	}
+	c.converters[to] = append(c.converters[to], converter{from: from, conv: conv})
+
+	return nil
+}
+
+// convert resolves an instance of type to by a converter. convertible is
+// false if there is no converter whose source type can be resolved.
+func (c *Container) convert(res *resolution, to reflect.Type) (value reflect.Value, convertible bool, err error) {
+	for cur := c; cur != nil; cur = cur.Parent() {
+		cur.RLock()
+		converters := cur.converters[to]
+		cur.RUnlock()
+
+		for _, conv := range converters {
+			id, exists, err := c.lookup(conv.from)
+			if err != nil {
+				return reflect.Value{}, true, err
+			}
+
+			if !exists {
+				continue
+			}
+
+			obj, err := c.get(res, id)
+			if err != nil {
+				return reflect.Value{}, true, err
+			}
+			res.resolved(id)
+
+			if res.dryRun {
+				return reflect.Zero(to), true, nil
+			}
+
+			converted := conv.conv(obj)
+			if converted == nil {
+				return reflect.Zero(to), true, nil
+			}
+
+			if t := reflect.TypeOf(converted); !t.AssignableTo(to) {
+				return reflect.Value{}, true, fmt.Errorf("the converter from %s to %s returns a %s", conv.from, to, t)
+			}
+
+			return reflect.ValueOf(converted), true, nil
+		}
+	}
+
+	return reflect.Value{}, false, nil
+}
+
+// fallback resolves the type which nothing is registered with from the
+// instance of its pointer or element type and then by a converter.
+// convertible is false if neither applies.
+func (c *Container) fallback(res *resolution, t reflect.Type) (value reflect.Value, convertible bool, err error) {
+	if value, derived, err := c.derive(res, t); derived {
+		return value, true, err
+	}
+
+	return c.convert(res, t)
+}
+
+// derive resolves T from the instance of *T, which is copied, and *T from
+// the instance of T, which is a pointer to a copy of the instance. derived
+// is false if neither is registered.
+func (c *Container) derive(res *resolution, t reflect.Type) (value reflect.Value, derived bool, err error) {
+	from := derivedFrom(t)
+	id, exists, err := c.lookup(from)
+	if err != nil {
+		return reflect.Value{}, true, err
+	}
+
+	if !exists {
+		return reflect.Value{}, false, nil
+	}
+
+	obj, err := c.get(res, id)
+	if err != nil {
+		return reflect.Value{}, true, err
+	}
+	res.resolved(id)
+
+	if res.dryRun {
+		return reflect.Zero(t), true, nil
+	}
+
+	instance := reflect.ValueOf(obj)
+	if t.Kind() == reflect.Ptr {
+		ptr := reflect.New(from)
+		if obj != nil {
+			ptr.Elem().Set(instance)
+		}
+		return ptr, true, nil
+	}
+
+	if obj == nil || instance.IsNil() {
+		return reflect.Value{}, true, fmt.Errorf("the instance of %s is a nil %s", id, from)
+	}
+
+	return instance.Elem(), true, nil
+}
+
+// derivedFrom returns the type which derive resolves t from
+func derivedFrom(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+
+	return reflect.PtrTo(t)
+}
@@ -0,0 +1,138 @@
+package objectcommander
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testPort int
+
+func TestRegisterConverter(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("port"), func() testPort { return 8080 })
+
+	if err := c.Invoke(func(int) {}); err == nil {
+		t.Fatal("int should not be resolved without a converter")
+	}
+
+	c.RegisterConverter(reflect.TypeOf(testPort(0)), reflect.TypeOf(0), func(v interface{}) interface{} {
+		return int(v.(testPort))
+	})
+
+	var got int
+	if err := c.Invoke(func(port int) { got = port }); err != nil || got != 8080 {
+		t.Errorf("the param should be converted: %d, %v", got, err)
+	}
+
+	var port int
+	if err := c.Assign(&port); err != nil || port != 8080 {
+		t.Errorf("the assign target should be converted: %d, %v", port, err)
+	}
+
+	c.RegisterConverter(reflect.TypeOf(testPort(0)), reflect.TypeOf(""), func(v interface{}) interface{} {
+		return 1.5
+	})
+	if err := c.Invoke(func(string) {}); err == nil {
+		t.Error("should reject a converted value of the wrong type")
+	}
+}
+
+func TestPrecedenceExactType(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("file"), func() *memoryFile { return &memoryFile{content: "file"} })
+	c.Register(Identity("writer"), func() testWriter { return &memoryFile{content: "writer"} })
+
+	var w testWriter
+	if err := c.Assign(&w); err != nil || w.(*memoryFile).content != "writer" {
+		t.Errorf("the interface binding should win for the interface: %v", err)
+	}
+
+	var f *memoryFile
+	if err := c.Assign(&f); err != nil || f.content != "file" {
+		t.Errorf("the concrete binding should win for the concrete type: %v", err)
+	}
+}
+
+func TestPrecedenceInterface(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("file"), func() *memoryFile { return &memoryFile{content: "file"} })
+	c.Register(Identity("name"), func() string { return "file" })
+	c.RegisterConverter(reflect.TypeOf(""), reflect.TypeOf((*testReader)(nil)).Elem(), func(v interface{}) interface{} {
+		return &memoryFile{content: "converted"}
+	})
+
+	var r testReader
+	if err := c.Assign(&r); err != nil || r.Read() != "file" {
+		t.Errorf("the implementing type should win over the converter: %v", err)
+	}
+}
+
+func TestPrecedencePointerAndElement(t *testing.T) {
+
+	type Config struct{ DSN string }
+	type Port int
+
+	c := NewContainer()
+	c.Register(Identity("config"), func() *Config { return &Config{DSN: "postgres://"} })
+	c.Register(Identity("port"), func() Port { return 5432 })
+	c.Register(Identity("raw port"), func() int { return 80 })
+	c.RegisterConverter(reflect.TypeOf(0), reflect.TypeOf(Config{}), func(v interface{}) interface{} {
+		return Config{DSN: "converted"}
+	})
+
+	err := c.Invoke(func(config Config, port *Port) {
+		if config.DSN != "postgres://" {
+			t.Errorf("the element should be derived from the pointer before the converter: %+v", config)
+		}
+		if *port != 5432 {
+			t.Errorf("the pointer should be derived from the element: %d", *port)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrecedenceBuildError(t *testing.T) {
+
+	type Config struct{ DSN string }
+
+	c := NewContainer()
+	c.Register(Identity("config"), func() (Config, error) { return Config{}, errors.New("no dsn") })
+	c.Register(Identity("config pointer"), func() *Config { return &Config{DSN: "postgres://"} })
+
+	var config Config
+	if err := c.Assign(&config); err == nil || config.DSN != "" {
+		t.Errorf("a failed build should not fall back to the pointer: %+v %v", config, err)
+	}
+}
+
+func TestPrecedenceConverter(t *testing.T) {
+
+	type Port int
+
+	c := NewContainer()
+	c.Register(Identity("raw port"), func() int { return 80 })
+	c.RegisterConverter(reflect.TypeOf(0), reflect.TypeOf(Port(0)), func(v interface{}) interface{} {
+		return Port(v.(int))
+	})
+
+	var port Port
+	if err := c.Assign(&port); err != nil || port != 80 {
+		t.Errorf("the converter should apply last: %d %v", port, err)
+	}
+}
+
+func TestPrecedenceError(t *testing.T) {
+
+	type Port int
+
+	c := NewContainer()
+	if err := c.Invoke(func(port Port) {}); err == nil {
+		t.Error("should fail if nothing resolves the type")
+	}
+}
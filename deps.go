@@ -0,0 +1,82 @@
+package objectcommander
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Deps marks a struct as a parameter object. A builder taking a struct
+// which embeds Deps gets it with the exported fields resolved from the
+// container like Fill, so a builder with many dependencies doesn't need
+// a long list of positional args.
+//
+//	type ServerDeps struct {
+//		objectcommander.Deps
+//		DB    *DB                           // resolved by the type
+//		Cache Cache `inject:"redis"`        // resolved by the identity
+//	}
+//
+//	func NewServer(deps ServerDeps) *Server
+//
+// Unlike Fill, the fields don't need the inject tag.
+type Deps struct{}
+
+// depsType is the reflect type of the Deps marker
+var depsType = reflect.TypeOf(Deps{})
+
+// isDeps reports whether t is a struct embedding Deps
+func isDeps(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.Anonymous && field.Type == depsType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// depsFields calls fn with every field of the parameter object which is
+// resolved from the container
+func depsFields(t reflect.Type, fn func(i int, field reflect.StructField)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type == depsType || field.PkgPath != "" {
+			continue
+		}
+		fn(i, field)
+	}
+}
+
+// depsFieldTypes returns the types of the fields resolved by the type
+func depsFieldTypes(t reflect.Type) []reflect.Type {
+	types := []reflect.Type{}
+	depsFields(t, func(_ int, field reflect.StructField) {
+		if tag := field.Tag.Get(injectTag); tag == "" {
+			types = append(types, field.Type)
+		}
+	})
+
+	return types
+}
+
+// buildDeps builds the parameter object of type t
+func (c *Container) buildDeps(res *resolution, t reflect.Type) (reflect.Value, error) {
+	deps := reflect.New(t).Elem()
+
+	var err error
+	depsFields(t, func(i int, field reflect.StructField) {
+		if err != nil {
+			return
+		}
+
+		if ferr := c.fillField(res, deps.Field(i), field.Tag.Get(injectTag)); ferr != nil {
+			err = fmt.Errorf("failed to inject field %s of %s: %w", field.Name, t, ferr)
+		}
+	})
+
+	return deps, err
+}
@@ -0,0 +1,54 @@
+package objectcommander
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testServerDeps struct {
+	Deps
+	Name string
+	Port int `inject:"port"`
+}
+
+type testServer struct {
+	name string
+	port int
+}
+
+func TestDeps(t *testing.T) {
+
+	c := NewContainer()
+	c.RegisterValue(Identity("name"), "api")
+	c.RegisterValue(Identity("port"), 8080)
+	c.Register(Identity("server"), func(deps testServerDeps) *testServer {
+		return &testServer{name: deps.Name, port: deps.Port}
+	})
+
+	var deps testServerDeps
+	if err := c.Invoke(func(d testServerDeps) { deps = d }); err != nil {
+		t.Fatal(err)
+	}
+
+	if deps.Name != "api" || deps.Port != 8080 {
+		t.Errorf("the fields should be resolved from the container: %+v", deps)
+	}
+
+	server, err := c.Get(Identity("server"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := server.(*testServer); s.name != "api" || s.port != 8080 {
+		t.Errorf("the builder should get the parameter object: %+v", s)
+	}
+
+	if ids := c.Dependents(reflect.TypeOf("")); !reflect.DeepEqual(ids, []Identity{"server"}) {
+		t.Errorf("the fields should be dependencies of the builder: %v", ids)
+	}
+
+	c.Unregister(Identity("name"))
+	if err := c.Invoke(func(d testServerDeps) {}); err == nil {
+		t.Error("a missing field should fail the resolution")
+	}
+}
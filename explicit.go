@@ -0,0 +1,37 @@
+package objectcommander
+
+import (
+	"context"
+	"fmt"
+)
+
+// NotBuiltError is returned by Get in a container created WithExplicitBuild
+// when the instance isn't built yet
+type NotBuiltError struct {
+	Name Identity
+}
+
+// Error returns the error message
+func (n NotBuiltError) Error() string {
+	return fmt.Sprintf("%s is not built yet, it should be created explicitly", n.Name)
+}
+
+// WithExplicitBuild disables building the instances lazily on Get. The
+// instances should be built by Create, or Warm and the eager start of a
+// bootstrap, before they are used, and Get returns a NotBuiltError for
+// an instance which isn't built yet. The dependencies of an instance are
+// built along with it. In this mode Create stores the instance it builds.
+func WithExplicitBuild() ContainerOption {
+	return func(c *Container) {
+		c.explicitBuild = true
+	}
+}
+
+// build gets the instance and builds it if needed even in a container
+// created WithExplicitBuild
+func (c *Container) build(name Identity) (interface{}, error) {
+	res := newResolution(context.Background())
+	res.explicit = true
+
+	return c.get(res, name)
+}
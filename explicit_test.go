@@ -0,0 +1,54 @@
+package objectcommander
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExplicitBuild(t *testing.T) {
+
+	type Config struct{ Name string }
+	type Server struct{ Config *Config }
+
+	c := NewContainer(WithExplicitBuild())
+	c.Register(Identity("config"), func() *Config { return &Config{Name: "api"} })
+	c.Register(Identity("server"), func(config *Config) *Server { return &Server{Config: config} })
+
+	var notBuilt NotBuiltError
+	if _, err := c.Get(Identity("server")); !errors.As(err, &notBuilt) || notBuilt.Name != Identity("server") {
+		t.Errorf("Get before Create should fail: %v", err)
+	}
+
+	created, err := c.Create(Identity("server"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := c.Get(Identity("server"))
+	if err != nil || server != created {
+		t.Errorf("Get after Create should return the created instance: %v", err)
+	}
+
+	if _, err := c.Get(Identity("config")); err != nil {
+		t.Errorf("the dependencies should be built along: %v", err)
+	}
+
+	if _, err := c.Get(Identity("nop")); errors.As(err, &notBuilt) {
+		t.Error("an unregistered identity should not be reported as not built")
+	}
+}
+
+func TestExplicitBuildWarm(t *testing.T) {
+
+	b := NewBootstrap(NewContainer(WithExplicitBuild()))
+	b.Boot([]Manager{NewManager(Identity("pool"), func() int { return 1 }, nil)})
+	defer b.Release()
+
+	if err := b.Warm(Identity("pool")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.GetContainer().Get(Identity("pool")); err != nil {
+		t.Errorf("the warmed instance should be built: %v", err)
+	}
+}
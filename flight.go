@@ -0,0 +1,537 @@
+package objectcommander
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// resolution carries the state of a resolution through the dependencies
+// which are built for it
+type resolution struct {
+	ctx     context.Context
+	path    []Identity // path is the identities being built, to detect cycles
+	created *creations // created tracks the instances built by an atomic resolution
+
+	// caller is the context of the flight of a detached build, it's done
+	// once every caller waiting for the build is gone and it stops the
+	// retries of the builder, see RegisterWithRetry
+	caller context.Context
+
+	// literals are the leading args given by GetWithArgs, they only apply
+	// to the builder being called and not to its dependencies
+	literals []reflect.Value
+	cleanup  func() // cleanup is returned by the builder being called
+	explicit bool   // explicit builds the instance WithExplicitBuild
+
+	// deps are the identities resolved for the builder being called, they
+	// are only recorded for the BeforeBuild hooks and WouldResolve
+	deps      []Identity
+	trackDeps bool
+
+	// dryRun resolves the identities without building them, see WouldResolve
+	dryRun bool
+}
+
+// resolved records the identity resolved for the builder being called
+func (r *resolution) resolved(id Identity) {
+	if r.trackDeps {
+		r.deps = append(r.deps, id)
+	}
+}
+
+// creations records the identities built during an atomic resolution. The
+// identities are recorded by the enclosing atomic resolutions as well.
+type creations struct {
+	parent *creations
+	names  []Identity
+	sync.Mutex
+}
+
+func (cr *creations) record(name Identity) {
+	for ; cr != nil; cr = cr.parent {
+		cr.Lock()
+		cr.names = append(cr.names, name)
+		cr.Unlock()
+	}
+}
+
+func newResolution(ctx context.Context) *resolution {
+	return &resolution{ctx: ctx}
+}
+
+// enter returns the resolution for building the dependencies of name
+func (r *resolution) enter(name Identity) *resolution {
+	return &resolution{
+		ctx:     r.ctx,
+		path:    append(r.path[:len(r.path):len(r.path)], name),
+		created: r.created,
+		caller:  r.caller,
+	}
+}
+
+// callerCtx returns the context of the caller of the resolution
+func (r *resolution) callerCtx() context.Context {
+	if r.caller != nil {
+		return r.caller
+	}
+
+	return r.ctx
+}
+
+// cycle returns an error if name is already being built by the resolution
+func (r *resolution) cycle(name Identity) error {
+	for i, id := range r.path {
+		if id != name {
+			continue
+		}
+
+		ids := make([]string, 0, len(r.path)-i+1)
+		for _, id := range r.path[i:] {
+			ids = append(ids, string(id))
+		}
+		ids = append(ids, string(name))
+
+		return fmt.Errorf("dependency cycle detected: %s", strings.Join(ids, " -> "))
+	}
+
+	return nil
+}
+
+// cachedInstance is an instance in the store with its definition
+type cachedInstance struct {
+	obj interface{}
+	def *definition
+}
+
+// loadCached returns the stored instance without taking the lock
+func (c *Container) loadCached(name Identity) (interface{}, bool) {
+	cached, exists := c.cached.Load(name)
+	if !exists {
+		return nil, false
+	}
+
+	instance := cached.(cachedInstance)
+	return instance.def.instance(instance.obj), true
+}
+
+// resetCached drops the mirror of the store, the caller holds the lock
+func (c *Container) resetCached() {
+	c.cached.Range(func(name, _ interface{}) bool {
+		c.cached.Delete(name)
+		return true
+	})
+}
+
+// flightKey is the instance being built, the instances of RegisterScoped
+// are built once per key of the scope
+type flightKey struct {
+	name   Identity
+	scope  string
+	scoped bool
+}
+
+// flight is a build of a singleton shared by every caller waiting for it
+type flight struct {
+	done chan struct{}
+	obj  interface{}
+	err  error
+
+	// ctx is cancelled once every waiter is gone, the waiters are counted
+	// under the lock of the container
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+
+	owner int64 // owner is the goroutine running the build
+}
+
+// goroutineID returns the id of the current goroutine, it's only used to
+// tell which goroutine waits for which build
+func goroutineID() int64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+
+	// the stack starts with "goroutine 123 ["
+	fields := bytes.Fields(buf[:n])
+	if len(fields) < 2 {
+		return 0
+	}
+	id, _ := strconv.ParseInt(string(fields[1]), 10, 64)
+
+	return id
+}
+
+// waitsFor reports whether waiting for the flight would wait for the
+// goroutine itself, e.g. the builder of a calls Get(b) and the builder of b
+// calls Get(a). Such a cycle isn't seen by the resolution since every Get
+// starts a new one. The caller holds the lock.
+func (c *Container) waitsFor(me int64, f *flight) bool {
+	owner := f.owner
+	for i := 0; i <= len(c.flights); i++ {
+		if owner == me {
+			return true
+		}
+
+		key, waiting := c.waiting[owner]
+		if !waiting {
+			return false
+		}
+		next, inflight := c.flights[key]
+		if !inflight {
+			return false
+		}
+		owner = next.owner
+	}
+
+	return false
+}
+
+// leave stops waiting for the flight and cancels it if nobody else waits
+func (c *Container) leave(f *flight) {
+	c.Lock()
+	f.waiters--
+	if f.waiters == 0 {
+		f.cancel()
+	}
+	c.Unlock()
+}
+
+// valuesOf keeps the values of the context but not its cancellation, so a
+// shared build sees the values of the caller which started it, e.g. the
+// key of RegisterScoped, without being cancelled with it
+type valuesOf struct{ context.Context }
+
+func (valuesOf) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (valuesOf) Done() <-chan struct{}       { return nil }
+func (valuesOf) Err() error                  { return nil }
+
+// GetCtx works like Get but stops waiting for the instance once ctx is
+// done and returns ctx.Err(). The build itself keeps running, so the other
+// callers waiting for the same instance still get it.
+func (c *Container) GetCtx(ctx context.Context, name Identity) (interface{}, error) {
+	return c.get(newResolution(ctx), name)
+}
+
+// Result is the instance or the error of a resolution done by GetAsync
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
+// GetAsync starts to resolve the instance on its own goroutine and returns
+// the channel which receives the result, so the caller can do other work
+// while a heavy instance is built. Like Get, the concurrent calls share
+// one build and a Get made in the meantime waits for it. A builder which
+// panics is received as an error.
+func (c *Container) GetAsync(name Identity) <-chan Result {
+	result := make(chan Result, 1)
+
+	if obj, exists := c.loadCached(name); exists {
+		result <- Result{Value: obj}
+		return result
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- Result{Err: fmt.Errorf("building %s panicked: %v", name, r)}
+			}
+		}()
+
+		obj, err := c.Get(name)
+		result <- Result{Value: obj, Err: err}
+	}()
+
+	return result
+}
+
+// get returns the cached instance or joins the build of it
+func (c *Container) get(res *resolution, name Identity) (interface{}, error) {
+	if res.dryRun {
+		if !c.has(name) {
+			return nil, fmt.Errorf("%s was not registered", name)
+		}
+		return nil, nil
+	}
+	if rec := c.recording(); rec != nil {
+		return rec.record(name, func() (interface{}, error) {
+			return c.resolve(res, name)
+		})
+	}
+
+	return c.resolve(res, name)
+}
+
+// resolve is get without the recording
+func (c *Container) resolve(res *resolution, name Identity) (interface{}, error) {
+	if err := res.cycle(name); err != nil {
+		return nil, err
+	}
+	c.depend(res, name)
+
+	if obj, exists := c.loadCached(name); exists {
+		return obj, nil
+	}
+
+	c.RLock()
+	if obj, exists := c.store[name]; exists {
+		def := c.defs[name]
+		c.RUnlock()
+		return def.instance(obj), nil
+	}
+	def, local := c.defs[name]
+	parent := c.parent
+	c.RUnlock()
+
+	// a child resolves what it doesn't define from its parent, so the
+	// instance is shared by the parent and all its children
+	if !local && parent != nil {
+		return parent.get(res, name)
+	}
+
+	if local && def.scopeKey != nil {
+		return c.getScoped(res, name, def)
+	}
+
+	// the dependencies are built along with an explicitly built instance
+	if local && c.explicitBuild && !res.explicit && len(res.path) == 0 {
+		return nil, NotBuiltError{Name: name}
+	}
+
+	return c.join(res, flightKey{name: name})
+}
+
+// join returns the stored instance of the key or waits for its build,
+// which is started if nobody builds it yet
+func (c *Container) join(res *resolution, key flightKey) (interface{}, error) {
+	c.Lock()
+	if obj, exists := c.stored(key); exists {
+		def := c.defs[key.name]
+		c.Unlock()
+		return def.instance(obj), nil
+	}
+
+	ctx := res.callerCtx()
+	me := goroutineID()
+
+	f, inflight := c.flights[key]
+	if inflight && c.waitsFor(me, f) {
+		c.Unlock()
+		return nil, fmt.Errorf("dependency cycle detected: %s is requested while it's being built", key.name)
+	}
+
+	// a cancellable caller should not be blocked by its own build, the
+	// owner of the build is then set by fly
+	async := !inflight && ctx.Done() != nil
+
+	if !inflight {
+		f = &flight{done: make(chan struct{})}
+
+		// a caller which can't be cancelled never leaves, so the build
+		// isn't cancellable either and its dependencies are built inline
+		f.ctx, f.cancel = valuesOf{ctx}, func() {}
+		if async {
+			f.ctx, f.cancel = context.WithCancel(valuesOf{ctx})
+		} else {
+			f.owner = me
+		}
+		if c.flights == nil {
+			c.flights = make(map[flightKey]*flight)
+		}
+		c.flights[key] = f
+	}
+	f.waiters++
+	if inflight || async {
+		if c.waiting == nil {
+			c.waiting = make(map[int64]flightKey)
+		}
+		c.waiting[me] = key
+	}
+	c.Unlock()
+
+	if async {
+		go c.fly(res, key, f, false)
+	} else if !inflight {
+		c.fly(res, key, f, true)
+	}
+
+	var err error
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if inflight || async {
+		c.Lock()
+		delete(c.waiting, me)
+		c.Unlock()
+	}
+
+	if err != nil {
+		c.leave(f)
+		return nil, err
+	}
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	c.RLock()
+	def := c.defs[key.name]
+	c.RUnlock()
+
+	return def.instance(f.obj), nil
+}
+
+// stored returns the instance of the key, the caller holds the lock
+func (c *Container) stored(key flightKey) (interface{}, bool) {
+	if key.scoped {
+		obj, exists := c.scopedMemo[key.name][key.scope]
+		return obj, exists
+	}
+
+	obj, exists := c.store[key.name]
+	return obj, exists
+}
+
+// fly builds the instance for the flight and stores it. A panic of the
+// builder fails the flight and it's raised again if repanic is true.
+func (c *Container) fly(res *resolution, key flightKey, f *flight, repanic bool) {
+	name := key.name
+	var built func()
+
+	if !repanic {
+		c.Lock()
+		f.owner = goroutineID()
+		c.Unlock()
+	}
+
+	defer func() {
+		r := recover()
+
+		c.Lock()
+		if r != nil {
+			f.err = fmt.Errorf("building %s panicked: %v", name, r)
+		}
+		delete(c.flights, key)
+		c.Unlock()
+
+		f.cancel()
+		close(f.done)
+
+		if r != nil && repanic {
+			panic(r)
+		}
+
+		// the waiters are released first, so the hook may resolve the
+		// instance itself
+		if r == nil && f.err == nil && built != nil {
+			built()
+		}
+	}()
+
+	c.RLock()
+	atomic := c.defs[name] != nil && c.defs[name].atomic
+	var warmup func(interface{}) error
+	if def := c.defs[name]; def != nil {
+		warmup = def.warmup
+		built = def.built
+	}
+	c.RUnlock()
+
+	created := res.created
+	if atomic {
+		created = &creations{parent: res.created}
+	}
+
+	// the build is shared, so it must not be cancelled with the caller
+	// but only once every caller waiting for it is gone
+	detached := &resolution{ctx: context.Background(), path: res.path, created: created, caller: f.ctx}
+
+	entered := detached.enter(name)
+	ret, err := c.create(entered, name)
+
+	if err != nil {
+		if atomic {
+			c.rollback(created)
+		}
+		f.err = err
+		return
+	}
+
+	obj := ret.Interface()
+
+	if warmup != nil {
+		if err := warmup(obj); err != nil {
+			if entered.cleanup != nil {
+				entered.cleanup()
+			}
+			if atomic {
+				c.rollback(created)
+			}
+			f.err = fmt.Errorf("failed to warm up %s: %w", name, err)
+			return
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	// the instance may be stored by With in the meantime, keep it so
+	// every caller shares the same singleton.
+	if existing, exists := c.stored(key); exists {
+		if entered.cleanup != nil {
+			c.transients = append(c.transients, entered.cleanup)
+		}
+		f.obj = existing
+		return
+	}
+
+	if key.scoped {
+		c.putScoped(key, obj, entered.cleanup)
+		f.obj = obj
+		return
+	}
+
+	c.put(name, obj)
+	if entered.cleanup != nil {
+		if c.cleanups == nil {
+			c.cleanups = make(map[Identity]func())
+		}
+		c.cleanups[name] = entered.cleanup
+	}
+	f.obj = obj
+	res.created.record(name)
+}
+
+// Atomic makes a failed build of the definition evict the instances which
+// were built for it during the same resolution, so a failed startup
+// doesn't leave a partially built graph in the container. The instances
+// which were built before are kept.
+func Atomic() RegisterOption {
+	return func(d *definition) {
+		d.atomic = true
+	}
+}
+
+// rollback evicts the instances built during the failed resolution
+func (c *Container) rollback(created *creations) {
+	created.Lock()
+	names := created.names
+	created.Unlock()
+
+	c.Lock()
+	defer c.Unlock()
+
+	for _, name := range names {
+		c.evict(name)
+	}
+}
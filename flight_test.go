@@ -0,0 +1,295 @@
+package objectcommander
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetSharesOneBuild(t *testing.T) {
+
+	var built int32
+	c := NewContainer()
+	c.Register(Identity("pool"), func() *struct{} {
+		atomic.AddInt32(&built, 1)
+		time.Sleep(20 * time.Millisecond)
+		return &struct{}{}
+	})
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = c.MustGet(Identity("pool"))
+		}(i)
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&built) != 1 {
+		t.Errorf("the instance should be built once but built %d times", built)
+	}
+
+	for _, r := range results {
+		if r != results[0] {
+			t.Error("every caller should get the same instance")
+		}
+	}
+}
+
+func TestGetCtx(t *testing.T) {
+
+	release := make(chan struct{})
+	c := NewContainer()
+	c.Register(Identity("slow"), func() string {
+		<-release
+		return "slow"
+	})
+
+	// a caller without a deadline starts the build
+	done := make(chan interface{})
+	go func() {
+		done <- c.MustGet(Identity("slow"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	if _, err := c.GetCtx(ctx, Identity("slow")); err != context.DeadlineExceeded {
+		t.Errorf("expected the deadline error but get %v", err)
+	}
+
+	if time.Since(started) > time.Second {
+		t.Error("the cancelled caller should return promptly")
+	}
+
+	close(release)
+	if (<-done).(string) != "slow" {
+		t.Error("the build should complete for the other caller")
+	}
+}
+
+func TestGetCtxStartsTheBuild(t *testing.T) {
+
+	release := make(chan struct{})
+	c := NewContainer()
+	c.Register(Identity("slow"), func() string {
+		<-release
+		return "slow"
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := c.GetCtx(ctx, Identity("slow")); err != context.Canceled {
+		t.Errorf("expected the cancelled error but get %v", err)
+	}
+
+	close(release)
+	if c.MustGet(Identity("slow")).(string) != "slow" {
+		t.Error("the abandoned build should still complete")
+	}
+}
+
+func TestDependencyCycle(t *testing.T) {
+
+	type A struct{}
+	type B struct{}
+
+	c := NewContainer()
+	c.Register(Identity("a"), func(B) A { return A{} })
+	c.Register(Identity("b"), func(A) B { return B{} })
+
+	_, err := c.Get(Identity("a"))
+	if err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("failed to detect the cycle: %v", err)
+	}
+}
+
+func TestNestedGetCycle(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("a"), func() (string, error) {
+		_, err := c.Get(Identity("b"))
+		return "a", err
+	})
+	c.Register(Identity("b"), func() (string, error) {
+		_, err := c.Get(Identity("a"))
+		return "b", err
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Get(Identity("a"))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "dependency cycle detected") {
+			t.Errorf("failed to detect the cycle of the nested calls: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the nested calls waiting for each other should not block")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := c.GetCtx(ctx, Identity("b")); err == nil {
+		t.Error("the cycle should be detected in a detached build as well")
+	}
+}
+
+func TestBuilderPanicReleasesWaiters(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("broken"), func() string {
+		panic("boom")
+	})
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("the panic should be raised to the caller")
+			}
+		}()
+		c.Get(Identity("broken"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := c.GetCtx(ctx, Identity("broken"))
+	if err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Errorf("expected the panic as an error but get %v", err)
+	}
+}
+
+func TestDependencyPanic(t *testing.T) {
+
+	type A struct{}
+	type B struct{}
+
+	c := NewContainer()
+	c.Register(Identity("a"), func(B) A { return A{} })
+	c.Register(Identity("b"), func() B { panic("boom") })
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("the panic of a dependency should be raised like the one of the builder")
+		}
+	}()
+
+	c.Get(Identity("a"))
+}
+
+func TestAtomic(t *testing.T) {
+
+	type Config struct{}
+	type B struct{}
+	type C struct{}
+	type A struct{}
+
+	newContainer := func(opts ...RegisterOption) *Container {
+		c := NewContainer()
+		c.Register(Identity("config"), func() Config { return Config{} })
+		c.Register(Identity("b"), func(Config) B { return B{} })
+		// c is not registered, so a fails after b is built
+		c.Register(Identity("a"), func(b B, c C) A { return A{} }, opts...)
+		return c
+	}
+
+	c := newContainer(Atomic())
+	c.MustGet(Identity("config"))
+
+	if _, err := c.Get(Identity("a")); err == nil {
+		t.Fatal("expected a to fail")
+	}
+
+	if _, exists := c.store[Identity("b")]; exists {
+		t.Error("b was built for a and should be evicted")
+	}
+
+	if _, exists := c.store[Identity("config")]; !exists {
+		t.Error("config was built before and should be kept")
+	}
+
+	c = newContainer()
+	c.Get(Identity("a"))
+
+	if _, exists := c.store[Identity("b")]; !exists {
+		t.Error("b should be kept without Atomic")
+	}
+}
+
+func BenchmarkParallelGet(b *testing.B) {
+
+	c := NewContainer()
+	names := make([]Identity, 16)
+	for i := range names {
+		names[i] = Identity(fmt.Sprintf("instance-%d", i))
+		c.RegisterValue(names[i], i)
+		c.MustGet(names[i])
+	}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Get(names[i%len(names)])
+			i++
+		}
+	})
+}
+
+func TestGetAsync(t *testing.T) {
+
+	type Index struct{ Entries int }
+
+	var built int32
+	release := make(chan struct{})
+	c := NewContainer()
+	c.Register(Identity("index"), func() *Index {
+		atomic.AddInt32(&built, 1)
+		<-release
+		return &Index{Entries: 42}
+	})
+	c.Register(Identity("broken"), func() int { panic("corrupted") })
+
+	first := c.GetAsync(Identity("index"))
+	second := c.GetAsync(Identity("index"))
+
+	select {
+	case <-first:
+		t.Fatal("GetAsync should return before the instance is built")
+	default:
+	}
+	close(release)
+
+	a, b := <-first, <-second
+	if a.Err != nil || b.Err != nil {
+		t.Fatal(a.Err, b.Err)
+	}
+
+	if a.Value != b.Value || a.Value.(*Index).Entries != 42 {
+		t.Errorf("both calls should receive the same instance: %v %v", a.Value, b.Value)
+	}
+
+	if atomic.LoadInt32(&built) != 1 {
+		t.Errorf("the calls should share one build: %d", built)
+	}
+
+	if got := c.MustGet(Identity("index")); got != a.Value {
+		t.Error("Get should return the instance built by GetAsync")
+	}
+
+	if r := <-c.GetAsync(Identity("broken")); r.Err == nil {
+		t.Error("a panicking builder should be received as an error")
+	}
+}
@@ -0,0 +1,61 @@
+package objectcommander
+
+import (
+	"fmt"
+)
+
+// FrozenContainerError is returned when the definitions of a frozen
+// container are modified
+type FrozenContainerError struct {
+	Op   string   // Op is the rejected operation, e.g. register
+	Name Identity // Name is the identity the operation was applied to, if any
+}
+
+// Error returns the error message
+func (f FrozenContainerError) Error() string {
+	if f.Name == "" {
+		return fmt.Sprintf("can't %s, the container is frozen", f.Op)
+	}
+
+	return fmt.Sprintf("can't %s %s, the container is frozen", f.Op, f.Name)
+}
+
+// Freeze makes the definitions of the container read-only, e.g. once the
+// application is booted. Register, Override and Unregister return a
+// FrozenContainerError afterwards while the instances can still be
+// resolved as usual.
+func (c *Container) Freeze() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.frozen = true
+}
+
+// Unfreeze allows the definitions to be modified again, it's mostly useful
+// for tests
+func (c *Container) Unfreeze() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.frozen = false
+}
+
+// SealedError is returned when a sealed definition is modified, see Sealed
+type SealedError struct {
+	Op   string   // Op is the rejected operation, e.g. override
+	Name Identity // Name is the sealed identity
+}
+
+// Error returns the error message
+func (s SealedError) Error() string {
+	return fmt.Sprintf("can't %s %s, it's sealed", s.Op, s.Name)
+}
+
+// Sealed protects the definition, e.g. of a signing key, from being
+// replaced at runtime. Override, Unregister and Invalidate return a
+// SealedError for it, and FlushALL, FlushByTag and Restore keep it.
+func Sealed() RegisterOption {
+	return func(d *definition) {
+		d.sealed = true
+	}
+}
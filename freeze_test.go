@@ -0,0 +1,110 @@
+package objectcommander
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("name"), func() string { return "api" })
+	c.Freeze()
+
+	var frozen FrozenContainerError
+
+	if err := c.Register(Identity("port"), func() int { return 80 }); !errors.As(err, &frozen) {
+		t.Errorf("register should be rejected: %v", err)
+	}
+
+	if err := c.RegisterValue(Identity("port"), 80); !errors.As(err, &frozen) {
+		t.Errorf("register a value should be rejected: %v", err)
+	}
+
+	if err := c.Override(Identity("name"), func() string { return "web" }); !errors.As(err, &frozen) {
+		t.Errorf("override should be rejected: %v", err)
+	}
+
+	if err := c.Unregister(Identity("name")); !errors.As(err, &frozen) || frozen.Name != Identity("name") {
+		t.Errorf("unregister should be rejected: %v", err)
+	}
+
+	if c.MustGet(Identity("name")).(string) != "api" {
+		t.Error("the instance should still be resolved")
+	}
+
+	if err := c.Invoke(func(name string) {}); err != nil {
+		t.Errorf("invoke should still work: %v", err)
+	}
+
+	c.Unfreeze()
+	if err := c.Register(Identity("port"), func() int { return 80 }); err != nil {
+		t.Errorf("register should work after unfreeze: %v", err)
+	}
+}
+
+func TestOverride(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("name"), func() string { return "api" })
+	c.MustGet(Identity("name"))
+
+	if err := c.Override(Identity("name"), func() string { return "web" }); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.MustGet(Identity("name")).(string) != "web" {
+		t.Error("the overridden builder should be used")
+	}
+
+	if err := c.Invoke(func(name string) {
+		if name != "web" {
+			t.Errorf("the type should resolve to the override: %s", name)
+		}
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSealed(t *testing.T) {
+
+	logger := &recordLogger{}
+	c := NewContainer()
+	c.SetLogger(logger)
+	c.RegisterValue(Identity("key"), "secret", Sealed(), WithTags("config"))
+	c.Register(Identity("port"), func() int { return 80 })
+	key := c.MustGet(Identity("key"))
+
+	var sealed SealedError
+
+	if err := c.Override(Identity("key"), func() string { return "forged" }); !errors.As(err, &sealed) {
+		t.Errorf("override should be rejected: %v", err)
+	}
+
+	if err := c.Unregister(Identity("key")); !errors.As(err, &sealed) || sealed.Name != Identity("key") {
+		t.Errorf("unregister should be rejected: %v", err)
+	}
+
+	if err := c.Invalidate(Identity("key")); !errors.As(err, &sealed) {
+		t.Errorf("invalidate should be rejected: %v", err)
+	}
+
+	c.FlushByTag("config")
+	if _, exists := c.store[Identity("key")]; !exists {
+		t.Error("FlushByTag should keep the sealed instance")
+	}
+
+	c.FlushALL()
+	if _, err := c.Get(Identity("port")); err == nil {
+		t.Error("FlushALL should clear the other definitions")
+	}
+
+	if c.MustGet(Identity("key")) != key || len(logger.messages) != 1 {
+		t.Errorf("FlushALL should keep the sealed definition with a warning: %v", logger.messages)
+	}
+
+	var resolved string
+	if err := c.Assign(&resolved); err != nil || resolved != "secret" {
+		t.Errorf("the sealed type should still be indexed: %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package objectcommander
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ResolveOr is the typed version of GetOr. The fallback is returned when
+// the instance can't be resolved or isn't a T.
+func ResolveOr[T any](c *Container, name Identity, fallback T) T {
+	result, err := c.Get(name)
+	if err != nil {
+		return fallback
+	}
+
+	typed, ok := result.(T)
+	if !ok {
+		return fallback
+	}
+
+	return typed
+}
+
+// typeOf returns the reflect type of T, it works for interface types as well
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Resolve is the typed version of GetByType
+func Resolve[T any](c *Container) (T, error) {
+	var zero T
+
+	result, err := c.GetByType(typeOf[T]())
+	if err != nil {
+		return zero, err
+	}
+
+	typed, ok := result.(T)
+	if !ok {
+		return zero, fmt.Errorf("the instance of %s is a %T", typeOf[T](), result)
+	}
+
+	return typed, nil
+}
+
+// TryResolve is the typed version of TryGet resolving by the type of T.
+// It returns the zero value and false if the instance can't be resolved.
+func TryResolve[T any](c *Container) (result T, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			var zero T
+			result, ok = zero, false
+		}
+	}()
+
+	result, err := Resolve[T](c)
+	if err != nil {
+		var zero T
+		return zero, false
+	}
+
+	return result, true
+}
+
+// Lazy defers the resolution of a dependency. A builder taking a Lazy[T]
+// parameter gets a handle to the container instead of the instance, so
+// the dependency is only built when Get is called.
+//
+//	c.Register(Identity("report"), func(mailer Lazy[*Mailer]) *Report {
+//		...
+//	})
+type Lazy[T any] struct {
+	container *Container
+}
+
+// Get resolves the dependency from the container
+func (l Lazy[T]) Get() (T, error) {
+	if l.container == nil {
+		var zero T
+		return zero, fmt.Errorf("lazy %s is not bound to a container", typeOf[T]())
+	}
+
+	return Resolve[T](l.container)
+}
+
+// lazyBinder is implemented by the pointer of every Lazy so buildParams
+// can bind the container without knowing T
+type lazyBinder interface {
+	bindContainer(c *Container)
+}
+
+var lazyBinderType = reflect.TypeOf((*lazyBinder)(nil)).Elem()
+
+func (l *Lazy[T]) bindContainer(c *Container) {
+	l.container = c
+}
+
+// IdentityFor derives a stable identity from the type T. Types with the
+// same name from different packages get different identities.
+func IdentityFor[T any]() Identity {
+	t := typeOf[T]()
+
+	base := t
+	for base.Name() == "" {
+		switch base.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+			base = base.Elem()
+			continue
+		}
+		break
+	}
+
+	if base.PkgPath() == "" {
+		return Identity(t.String())
+	}
+
+	return Identity(base.PkgPath() + ":" + t.String())
+}
+
+// RegisterFor registers the builder under the identity derived from T.
+// The builder should build an instance assignable to T.
+func RegisterFor[T any](c *Container, build Builder, opts ...RegisterOption) error {
+	if err := ValidateBuilder(build); err != nil {
+		return err
+	}
+
+	if out := (&definition{build: build}).outType(); !out.AssignableTo(typeOf[T]()) {
+		return fmt.Errorf("builder returns %s which is not assignable to %s", out, typeOf[T]())
+	}
+
+	return c.Register(IdentityFor[T](), build, opts...)
+}
+
+// ResolveFor gets the instance registered by RegisterFor
+func ResolveFor[T any](c *Container) (T, error) {
+	var zero T
+
+	result, err := c.Get(IdentityFor[T]())
+	if err != nil {
+		return zero, err
+	}
+
+	typed, ok := result.(T)
+	if !ok {
+		return zero, fmt.Errorf("the instance of %s is a %T", IdentityFor[T](), result)
+	}
+
+	return typed, nil
+}
+
+// ResolveGroup is the typed version of GetByTag, e.g. to collect the
+// plugins tagged with "handler" as a []Handler. It fails if one of the
+// tagged instances isn't a T.
+func ResolveGroup[T any](c *Container, tag string) ([]T, error) {
+	results, err := c.GetByTag(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	group := make([]T, 0, len(results))
+	for _, result := range results {
+		typed, ok := result.(T)
+		if !ok {
+			return nil, fmt.Errorf("an instance tagged with %s is a %T instead of %s", tag, result, typeOf[T]())
+		}
+		group = append(group, typed)
+	}
+
+	return group, nil
+}
+
+// lazyElem returns T if the type is a Lazy[T]
+func lazyElem(t reflect.Type) (reflect.Type, bool) {
+	if !reflect.PtrTo(t).Implements(lazyBinderType) {
+		return nil, false
+	}
+
+	get, _ := t.MethodByName("Get")
+	return get.Type.Out(0), true
+}
@@ -0,0 +1,161 @@
+package objectcommander
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveOr(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("timeout"), func() int { return 30 })
+
+	if ResolveOr(c, Identity("timeout"), 10) != 30 {
+		t.Error("should resolve the registered instance")
+	}
+
+	if ResolveOr(c, Identity("retries"), 3) != 3 {
+		t.Error("should fall back for an unregistered instance")
+	}
+
+	if ResolveOr(c, Identity("timeout"), "10s") != "10s" {
+		t.Error("should fall back when the instance is not the expected type")
+	}
+}
+
+func TestResolve(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("port"), func() int { return 8080 })
+
+	port, err := Resolve[int](c)
+	if err != nil || port != 8080 {
+		t.Errorf("failed to resolve the port: %v", err)
+	}
+
+	if _, err := Resolve[string](c); err == nil {
+		t.Error("should fail to resolve an unregistered type")
+	}
+}
+
+func TestLazy(t *testing.T) {
+
+	type Mailer struct{ Host string }
+	type Report struct {
+		mailer Lazy[*Mailer]
+	}
+
+	built := 0
+	c := NewContainer()
+	c.Register(Identity("mailer"), func() *Mailer {
+		built++
+		return &Mailer{Host: "smtp"}
+	})
+	c.Register(Identity("report"), func(mailer Lazy[*Mailer]) *Report {
+		return &Report{mailer: mailer}
+	})
+
+	report := c.MustGet(Identity("report")).(*Report)
+	if built != 0 {
+		t.Error("the lazy dependency should not be built eagerly")
+	}
+
+	mailer, err := report.mailer.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mailer.Host != "smtp" || built != 1 {
+		t.Error("failed to resolve the lazy dependency on demand")
+	}
+
+	var unbound Lazy[*Mailer]
+	if _, err := unbound.Get(); err == nil {
+		t.Error("an unbound lazy should return an error")
+	}
+}
+
+func TestIdentityFor(t *testing.T) {
+
+	type Primary struct{ Name string }
+	type Replica struct{ Name string }
+
+	if IdentityFor[*Primary]() == IdentityFor[*Replica]() {
+		t.Error("different types should have different identities")
+	}
+
+	if IdentityFor[*Primary]() != IdentityFor[*Primary]() {
+		t.Error("the identity should be stable")
+	}
+
+	c := NewContainer()
+	if err := RegisterFor[*Primary](c, func() *Primary { return &Primary{Name: "primary"} }); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterFor[*Replica](c, func() *Replica { return &Replica{Name: "replica"} }); err != nil {
+		t.Fatal(err)
+	}
+
+	primary, err := ResolveFor[*Primary](c)
+	if err != nil || primary.Name != "primary" {
+		t.Errorf("failed to resolve by the type identity: %v", err)
+	}
+
+	replica, err := ResolveFor[*Replica](c)
+	if err != nil || replica.Name != "replica" {
+		t.Errorf("failed to resolve by the type identity: %v", err)
+	}
+
+	if err := RegisterFor[int](c, func() string { return "" }); err == nil {
+		t.Error("should reject a builder of another type")
+	}
+}
+
+func TestTryResolve(t *testing.T) {
+
+	type Cache struct{ Name string }
+	type Metrics struct{}
+
+	c := NewContainer()
+	c.Register(Identity("cache"), func() *Cache { return &Cache{Name: "redis"} })
+	c.Register(Identity("metrics"), func() *Metrics { panic("no collector") })
+
+	if cache, ok := TryResolve[*Cache](c); !ok || cache.Name != "redis" {
+		t.Error("should resolve the instance")
+	}
+
+	if metrics, ok := TryResolve[*Metrics](c); ok || metrics != nil {
+		t.Error("a panicking builder should yield the zero value")
+	}
+
+	if _, ok := TryResolve[string](c); ok {
+		t.Error("an unregistered type should not be resolved")
+	}
+}
+
+func TestResolveGroup(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("logging"), func() testHandler { return routeHandler("logging") }, WithTags("handler"))
+	c.Register(Identity("auth"), func() testHandler { return routeHandler("auth") }, WithTags("handler"), WithPriority(10))
+	c.Register(Identity("metrics"), func() testHandler { return routeHandler("metrics") }, WithTags("handler"))
+
+	handlers, err := ResolveGroup[testHandler](c, "handler")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := []string{}
+	for _, h := range handlers {
+		routes = append(routes, h.Route())
+	}
+
+	if !reflect.DeepEqual(routes, []string{"auth", "logging", "metrics"}) {
+		t.Errorf("unexpected handlers: %v", routes)
+	}
+
+	c.Register(Identity("recovery"), func() string { return "recovery" }, WithTags("handler"))
+	if _, err := ResolveGroup[testHandler](c, "handler"); err == nil {
+		t.Error("should fail if a tagged instance isn't a testHandler")
+	}
+}
@@ -0,0 +1,31 @@
+package objectcommander
+
+import (
+	"context"
+)
+
+// Checker is implemented by the instances which can report their health
+type Checker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// HealthCheck runs the check of every built instance implementing Checker
+// and returns the result by the identity, a healthy instance maps to nil.
+// The instances which aren't built yet are not checked.
+func (c *Container) HealthCheck(ctx context.Context) map[Identity]error {
+	c.RLock()
+	checkers := make(map[Identity]Checker)
+	for name, obj := range c.store {
+		if checker, ok := obj.(Checker); ok {
+			checkers[name] = checker
+		}
+	}
+	c.RUnlock()
+
+	results := make(map[Identity]error, len(checkers))
+	for name, checker := range checkers {
+		results[name] = checker.HealthCheck(ctx)
+	}
+
+	return results
+}
@@ -0,0 +1,39 @@
+package objectcommander
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type fakeChecker struct{ err error }
+
+func (f *fakeChecker) HealthCheck(ctx context.Context) error { return f.err }
+
+func TestHealthCheck(t *testing.T) {
+
+	down := errors.New("connection refused")
+
+	c := NewContainer()
+	c.Register(Identity("db"), func() *fakeChecker { return &fakeChecker{} })
+	c.Register(Identity("cache"), func() *fakeChecker { return &fakeChecker{err: down} })
+	c.Register(Identity("queue"), func() *fakeChecker { return &fakeChecker{err: down} })
+	c.Register(Identity("name"), func() string { return "api" })
+
+	c.MustGet(Identity("db"))
+	c.MustGet(Identity("cache"))
+	c.MustGet(Identity("name"))
+
+	results := c.HealthCheck(context.Background())
+	if len(results) != 2 {
+		t.Fatalf("only the built checkers should be checked: %v", results)
+	}
+
+	if err, exists := results[Identity("db")]; !exists || err != nil {
+		t.Error("db should be healthy")
+	}
+
+	if results[Identity("cache")] != down {
+		t.Error("cache should report its error")
+	}
+}
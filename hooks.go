@@ -0,0 +1,12 @@
+package objectcommander
+
+// BeforeBuild adds a hook which is called right before a builder runs with
+// the identities of the dependencies resolved for it, e.g. to trace the
+// startup. The hooks are called without the lock held, in the order they
+// were added. The deps must not be modified.
+func (c *Container) BeforeBuild(hook func(name Identity, deps []Identity)) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.beforeBuild = append(c.beforeBuild[:len(c.beforeBuild):len(c.beforeBuild)], hook)
+}
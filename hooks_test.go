@@ -0,0 +1,37 @@
+package objectcommander
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBeforeBuild(t *testing.T) {
+
+	type Server struct{}
+
+	c := NewContainer()
+	c.RegisterValue(Identity("name"), "api")
+	c.RegisterValue(Identity("port"), 8080)
+	c.Register(Identity("server"), func(name string, port int) *Server { return &Server{} })
+
+	traces := map[Identity][]Identity{}
+	order := []Identity{}
+	c.BeforeBuild(func(name Identity, deps []Identity) {
+		traces[name] = append([]Identity(nil), deps...)
+		order = append(order, name)
+	})
+
+	c.MustGet(Identity("server"))
+
+	if !reflect.DeepEqual(traces[Identity("server")], []Identity{"name", "port"}) {
+		t.Errorf("unexpected dependencies: %v", traces[Identity("server")])
+	}
+
+	if !reflect.DeepEqual(order, []Identity{"name", "port", "server"}) {
+		t.Errorf("the hook should run before each builder: %v", order)
+	}
+
+	if deps := traces[Identity("name")]; len(deps) != 0 {
+		t.Errorf("a builder without params has no dependencies: %v", deps)
+	}
+}
@@ -0,0 +1,138 @@
+package objectcommander
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+const (
+	// injectTag is the struct tag which marks a field to be filled
+	injectTag = "inject"
+	// groupTag fills a slice field with every instance of its element type
+	groupTag = "group"
+)
+
+// GetAllByType returns every instance registered with the type ordered by
+// the priority and then the order of registration
+func (c *Container) GetAllByType(t reflect.Type) ([]interface{}, error) {
+	ids := c.allByType(t)
+
+	results := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		result, err := c.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// allByType returns the identities registered with the type ordered by
+// the priority
+func (c *Container) allByType(t reflect.Type) []Identity {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.sortByPriority(append([]Identity(nil), c.typeToIdentity[t]...))
+}
+
+// Fill populates the exported fields tagged with `inject` of the struct
+// which target points to.
+//
+//	type Server struct {
+//		DB       *DB       `inject:""`       // resolved by the type
+//		Cache    Cache     `inject:"redis"`  // resolved by the identity
+//		Handlers []Handler `inject:"group"`  // every instance of Handler
+//	}
+//
+// Fields without the tag are left untouched.
+func (c *Container) Fill(target interface{}) error {
+	value := reflect.ValueOf(target)
+	if !value.IsValid() || value.Kind() != reflect.Ptr || value.IsNil() {
+		return errors.New("fill target should be a non-nil pointer to a struct")
+	}
+
+	value = value.Elem()
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("fill target should be a pointer to a struct instead of %s", value.Type())
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag, tagged := field.Tag.Lookup(injectTag)
+		if !tagged {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if !fieldValue.CanSet() {
+			return fmt.Errorf("field %s can't be injected because it's unexported", field.Name)
+		}
+
+		if err := c.fillField(newResolution(context.Background()), fieldValue, tag); err != nil {
+			return fmt.Errorf("failed to inject field %s: %w", field.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// fillField resolves the field by its inject tag
+func (c *Container) fillField(res *resolution, field reflect.Value, tag string) error {
+	var id Identity
+
+	switch {
+	case tag == groupTag && field.Kind() == reflect.Slice && res.dryRun:
+		for _, id := range c.allByType(field.Type().Elem()) {
+			res.resolved(id)
+		}
+
+		return nil
+	case tag == groupTag && field.Kind() == reflect.Slice:
+		results, err := c.GetAllByType(field.Type().Elem())
+		if err != nil {
+			return err
+		}
+
+		elem := field.Type().Elem()
+		group := reflect.MakeSlice(field.Type(), 0, len(results))
+		for _, r := range results {
+			if r == nil {
+				group = reflect.Append(group, reflect.Zero(elem))
+				continue
+			}
+
+			if t := reflect.TypeOf(r); !t.AssignableTo(elem) {
+				return TypeMismatchError{Expected: elem, Actual: t}
+			}
+			group = reflect.Append(group, reflect.ValueOf(r))
+		}
+		field.Set(group)
+
+		return nil
+	case tag == "":
+		var exists bool
+		var err error
+		if id, exists, err = c.lookup(field.Type()); err != nil {
+			return err
+		}
+
+		if !exists {
+			return unregisteredTypeError{t: field.Type()}
+		}
+	default:
+		id = Identity(tag)
+	}
+
+	result, err := c.get(res, id)
+	if err != nil {
+		return err
+	}
+	res.resolved(id)
+
+	return set(id, field, result)
+}
@@ -0,0 +1,85 @@
+package objectcommander
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testHandler interface {
+	Route() string
+}
+
+type routeHandler string
+
+func (r routeHandler) Route() string { return string(r) }
+
+func TestFill(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("name"), func() string { return "api" })
+	c.Register(Identity("users"), func() testHandler { return routeHandler("/users") })
+	c.Register(Identity("orders"), func() testHandler { return routeHandler("/orders") })
+
+	var server struct {
+		Name     string        `inject:""`
+		Primary  testHandler   `inject:"orders"`
+		Handlers []testHandler `inject:"group"`
+		Ports    []int
+	}
+
+	if err := c.Fill(&server); err != nil {
+		t.Fatal(err)
+	}
+
+	if server.Name != "api" {
+		t.Error("failed to inject the field by type")
+	}
+
+	if server.Primary.Route() != "/orders" {
+		t.Error("failed to inject the field by identity")
+	}
+
+	if len(server.Handlers) != 2 || server.Handlers[0].Route() != "/users" || server.Handlers[1].Route() != "/orders" {
+		t.Errorf("failed to inject the group: %v", server.Handlers)
+	}
+
+	if server.Ports != nil {
+		t.Error("untagged fields should be left untouched")
+	}
+
+	if err := c.Fill(server); err == nil {
+		t.Error("should reject a non-pointer target")
+	}
+}
+
+func TestFillTypeMismatch(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("port"), func() int { return 80 })
+
+	var server struct {
+		Name string `inject:"port"`
+	}
+
+	var mismatch TypeMismatchError
+	if err := c.Fill(&server); !errors.As(err, &mismatch) || mismatch.Name != Identity("port") {
+		t.Errorf("a tag naming an instance of another type should be rejected: %v", err)
+	}
+}
+
+func TestGetAllByType(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("users"), func() testHandler { return routeHandler("/users") })
+	c.Register(Identity("orders"), func() testHandler { return routeHandler("/orders") })
+
+	handlers, err := c.GetAllByType(reflect.TypeOf((*testHandler)(nil)).Elem())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(handlers) != 2 {
+		t.Errorf("expected two handlers but get %d", len(handlers))
+	}
+}
@@ -0,0 +1,73 @@
+package objectcommander
+
+import (
+	"fmt"
+)
+
+// WithReactiveInvalidation makes the container track which instances are
+// built from which dependencies, so invalidating an instance invalidates
+// every cached instance built from it as well, transitively.
+func WithReactiveInvalidation() ContainerOption {
+	return func(c *Container) {
+		c.reactive = true
+	}
+}
+
+// depend records that the instance being built by res depends on name
+func (c *Container) depend(res *resolution, name Identity) {
+	// reactive is only set when the container is created
+	if !c.reactive || len(res.path) == 0 {
+		return
+	}
+	dependent := res.path[len(res.path)-1]
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.dependents == nil {
+		c.dependents = make(map[Identity]map[Identity]bool)
+	}
+	if c.dependents[name] == nil {
+		c.dependents[name] = make(map[Identity]bool)
+	}
+	c.dependents[name][dependent] = true
+}
+
+// Invalidate drops the cached instance of the identity so the next Get
+// builds it again, e.g. after the config it was built from changed. The
+// instance is dropped without being closed. With WithReactiveInvalidation,
+// the instances built from it are dropped as well.
+func (c *Container) Invalidate(name Identity) error {
+	c.Lock()
+	defer c.Unlock()
+
+	def, exists := c.defs[name]
+	if !exists {
+		return fmt.Errorf("%s was not registered", name)
+	}
+
+	if def.sealed {
+		return SealedError{Op: "invalidate", Name: name}
+	}
+
+	c.invalidate(name)
+
+	return nil
+}
+
+// invalidate evicts the instance and its dependents, the caller holds the
+// lock. A sealed instance is kept.
+func (c *Container) invalidate(name Identity) {
+	if def, exists := c.defs[name]; exists && def.sealed {
+		return
+	}
+
+	c.evict(name)
+
+	dependents := c.dependents[name]
+	delete(c.dependents, name)
+
+	for dependent := range dependents {
+		c.invalidate(dependent)
+	}
+}
@@ -0,0 +1,63 @@
+package objectcommander
+
+import (
+	"testing"
+)
+
+func TestReactiveInvalidation(t *testing.T) {
+
+	type Config struct{ Version int }
+	type Repo struct{ Config *Config }
+	type Service struct{ Repo *Repo }
+
+	version := 0
+	c := NewContainer(WithReactiveInvalidation())
+	c.Register(Identity("config"), func() *Config {
+		version++
+		return &Config{Version: version}
+	})
+	c.Register(Identity("repo"), func(config *Config) *Repo { return &Repo{Config: config} })
+	c.Register(Identity("service"), func(repo *Repo) *Service { return &Service{Repo: repo} })
+	c.Register(Identity("name"), func() string { return "api" })
+
+	service := c.MustGet(Identity("service")).(*Service)
+	name := c.MustGet(Identity("name"))
+
+	if err := c.Invalidate(Identity("config")); err != nil {
+		t.Fatal(err)
+	}
+
+	rebuilt := c.MustGet(Identity("service")).(*Service)
+	if rebuilt == service || rebuilt.Repo.Config.Version != 2 {
+		t.Error("the dependents should be invalidated transitively")
+	}
+
+	if _, exists := c.store[Identity("name")]; !exists || c.MustGet(Identity("name")) != name {
+		t.Error("the unrelated instance should be kept")
+	}
+
+	if err := c.Invalidate(Identity("nop")); err == nil {
+		t.Error("should reject an unregistered identity")
+	}
+}
+
+func TestInvalidateWithoutReactive(t *testing.T) {
+
+	type Config struct{ Name string }
+	type Service struct{ Config *Config }
+
+	c := NewContainer()
+	c.Register(Identity("config"), func() *Config { return &Config{} })
+	c.Register(Identity("service"), func(config *Config) *Service { return &Service{Config: config} })
+
+	service := c.MustGet(Identity("service")).(*Service)
+	c.Invalidate(Identity("config"))
+
+	if c.MustGet(Identity("config")) == service.Config {
+		t.Error("the invalidated instance should be rebuilt")
+	}
+
+	if c.MustGet(Identity("service")) != service {
+		t.Error("the dependents should be kept without the reactive invalidation")
+	}
+}
@@ -0,0 +1,65 @@
+package objectcommander
+
+import (
+	"fmt"
+	"sync"
+)
+
+// RegisterLazyDef registers a definition whose builder is generated by the
+// provider the first time the identity is requested. It's useful when
+// generating the builder is expensive, e.g. for dynamically loaded plugins.
+// The type of the instance is unknown until the provider runs, so the
+// definition can only be resolved by its identity before that.
+func (c *Container) RegisterLazyDef(name Identity, provider func() Builder) error {
+	if provider == nil {
+		return fmt.Errorf("the provider of %s should not be nil", name)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.frozen {
+		return FrozenContainerError{Op: "register", Name: name}
+	}
+
+	if _, exists := c.defs[name]; exists {
+		return AlreadyRegisteredError{
+			Name: name,
+			msg:  fmt.Sprintf("%s was already registered", name),
+		}
+	}
+
+	c.seq++
+	c.defs[name] = &definition{
+		seq:          c.seq,
+		provider:     provider,
+		providerOnce: &sync.Once{},
+	}
+
+	return nil
+}
+
+// provide runs the provider of a lazy definition once and indexes the type
+// of the builder it returns. The lock must not be held by the caller.
+func (c *Container) provide(name Identity, def *definition) error {
+	if def.provider == nil {
+		return nil
+	}
+
+	def.providerOnce.Do(func() {
+		build := def.provider()
+		if err := ValidateBuilder(build); err != nil {
+			def.providerErr = fmt.Errorf("the provider of %s returns an invalid builder: %w", name, err)
+			return
+		}
+
+		c.Lock()
+		def.build = build
+		retType := def.outType()
+		c.typeToIdentity[retType] = append(c.typeToIdentity[retType], name)
+		c.resetParamCache()
+		c.Unlock()
+	})
+
+	return def.providerErr
+}
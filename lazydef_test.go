@@ -0,0 +1,60 @@
+package objectcommander
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegisterLazyDef(t *testing.T) {
+
+	provided := 0
+	c := NewContainer()
+	err := c.RegisterLazyDef(Identity("plugin"), func() Builder {
+		provided++
+		return func() string { return "plugin" }
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if provided != 0 {
+		t.Error("the provider should not run on registration")
+	}
+
+	if c.MustGet(Identity("plugin")).(string) != "plugin" {
+		t.Error("failed to build from the provided builder")
+	}
+
+	if _, err := c.Create(Identity("plugin")); err != nil {
+		t.Error(err)
+	}
+
+	if provided != 1 {
+		t.Errorf("the provider should run once but run %d times", provided)
+	}
+
+	// the type is indexed once the builder is provided
+	if _, err := c.GetByType(reflect.TypeOf("")); err != nil {
+		t.Error(err)
+	}
+
+	if err := c.RegisterLazyDef(Identity("plugin"), func() Builder { return nil }); err == nil {
+		t.Error("failed to detect duplicated registration")
+	}
+
+	c.RegisterLazyDef(Identity("broken"), func() Builder { return "not a builder" })
+	if _, err := c.Get(Identity("broken")); err == nil {
+		t.Error("should fail on an invalid provided builder")
+	}
+}
+
+func TestUnregisterLazyDef(t *testing.T) {
+
+	c := NewContainer()
+	c.RegisterLazyDef(Identity("plugin"), func() Builder { return nil })
+	c.Unregister(Identity("plugin"))
+
+	if _, err := c.Get(Identity("plugin")); err == nil {
+		t.Error("failed to unregister a lazy definition")
+	}
+}
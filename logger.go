@@ -0,0 +1,27 @@
+package objectcommander
+
+// Logger receives the warnings of the container. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// SetLogger sets the logger used to report warnings. A nil logger disables
+// the warnings.
+func (c *Container) SetLogger(logger Logger) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.logger = logger
+}
+
+// logf writes a warning to the logger if there is one. The lock must not
+// be held by the caller.
+func (c *Container) logf(format string, v ...interface{}) {
+	c.RLock()
+	logger := c.logger
+	c.RUnlock()
+
+	if logger != nil {
+		logger.Printf(format, v...)
+	}
+}
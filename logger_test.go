@@ -0,0 +1,67 @@
+package objectcommander
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// recordLogger keeps the logged messages for assertions
+type recordLogger struct {
+	messages []string
+}
+
+func (r *recordLogger) Printf(format string, v ...interface{}) {
+	r.messages = append(r.messages, fmt.Sprintf(format, v...))
+}
+
+func TestWarnDuplicatedType(t *testing.T) {
+
+	logger := &recordLogger{}
+	c := NewContainer()
+	c.SetLogger(logger)
+
+	c.Register(Identity("primary"), func() string { return "primary" })
+	if len(logger.messages) != 0 {
+		t.Error("the first registration of a type should not warn")
+	}
+
+	if err := c.Register(Identity("replica"), func() string { return "replica" }); err != nil {
+		t.Error("registering the same type twice should not be an error")
+	}
+
+	if len(logger.messages) != 1 {
+		t.Fatalf("expected one warning but get %v", logger.messages)
+	}
+
+	msg := logger.messages[0]
+	if !strings.Contains(msg, "primary") || !strings.Contains(msg, "replica") {
+		t.Errorf("the warning should name both identities: %s", msg)
+	}
+}
+
+func TestWarnVariadicInvoke(t *testing.T) {
+
+	logger := &recordLogger{}
+	c := NewContainer()
+	c.SetLogger(logger)
+	c.RegisterValue(Identity("name"), "api")
+
+	var got []string
+	if err := c.Invoke(func(name string, formats ...string) { got = formats }); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 0 {
+		t.Errorf("the variadic args should be empty: %v", got)
+	}
+
+	if len(logger.messages) != 1 || !strings.Contains(logger.messages[0], "without its variadic args") {
+		t.Errorf("invoking a variadic function should warn: %v", logger.messages)
+	}
+
+	c.Invoke(func(name string) {})
+	if len(logger.messages) != 1 {
+		t.Error("a non-variadic function should not warn")
+	}
+}
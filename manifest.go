@@ -0,0 +1,65 @@
+package objectcommander
+
+import (
+	"sort"
+)
+
+// ManifestEntry describes a definition of the container. It only holds
+// strings so the manifest can be marshaled, e.g. to JSON for a golden test
+// or to review how the wiring changes between versions.
+type ManifestEntry struct {
+	Identity     Identity `json:"identity"`
+	Type         string   `json:"type"`                   // Type is empty for a lazy definition which isn't provided yet
+	Dependencies []string `json:"dependencies,omitempty"` // Dependencies are the types of the params of the builder
+	Tags         []string `json:"tags,omitempty"`
+	Scope        string   `json:"scope"` // Scope is one of singleton, weak, scoped and args
+}
+
+// Manifest describes every definition ordered by the registration. Nothing
+// is built, and the definitions of the parent aren't included.
+func (c *Container) Manifest() []ManifestEntry {
+	c.RLock()
+	defer c.RUnlock()
+
+	names := make([]Identity, 0, len(c.defs))
+	for name := range c.defs {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return c.defs[names[i]].seq < c.defs[names[j]].seq })
+
+	entries := make([]ManifestEntry, 0, len(names))
+	for _, name := range names {
+		def := c.defs[name]
+		entry := ManifestEntry{Identity: name, Scope: def.scope()}
+
+		if t := def.outType(); t != nil {
+			entry.Type = t.String()
+		}
+
+		for _, t := range def.dependencyTypes() {
+			entry.Dependencies = append(entry.Dependencies, t.String())
+		}
+
+		if len(def.tags) > 0 {
+			entry.Tags = append([]string(nil), def.tags...)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// scope names how the instances of the definition are cached
+func (d *definition) scope() string {
+	switch {
+	case d.withArgs:
+		return "args"
+	case d.scopeKey != nil:
+		return "scoped"
+	case d.weak:
+		return "weak"
+	}
+
+	return "singleton"
+}
@@ -0,0 +1,39 @@
+package objectcommander
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+
+	type Config struct{ DSN string }
+	type DB struct{ Config *Config }
+
+	c := NewContainer()
+	c.Register(Identity("config"), func() *Config { return &Config{} }, WithTags("settings"))
+	c.Register(Identity("db"), func(config *Config) (*DB, error) { return &DB{Config: config}, nil }, WeakSingleton())
+	c.RegisterScoped(Identity("tenant"), func(ctx context.Context) string { return "" }, func(db *DB, config Lazy[*Config]) string { return "" })
+	c.RegisterWithArgs(Identity("client"), func(region string) *DB { return nil })
+
+	manifest, err := json.Marshal(c.Manifest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[` +
+		`{"identity":"config","type":"*objectcommander.Config","tags":["settings"],"scope":"singleton"},` +
+		`{"identity":"db","type":"*objectcommander.DB","dependencies":["*objectcommander.Config"],"scope":"weak"},` +
+		`{"identity":"tenant","type":"string","dependencies":["*objectcommander.DB","*objectcommander.Config"],"scope":"scoped"},` +
+		`{"identity":"client","type":"*objectcommander.DB","dependencies":["string"],"scope":"args"}` +
+		`]`
+
+	if string(manifest) != expected {
+		t.Errorf("unexpected manifest:\n%s", manifest)
+	}
+
+	if _, built := c.Peek(Identity("config")); built {
+		t.Error("the manifest should not build anything")
+	}
+}
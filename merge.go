@@ -0,0 +1,132 @@
+package objectcommander
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// MergeOption customizes how Merge treats an identity registered in both
+// containers
+type MergeOption func(*mergeConfig)
+
+type mergeConfig struct {
+	skip     bool
+	override bool
+}
+
+// SkipExisting makes Merge keep the definition of the receiver when both
+// containers register the identity
+func SkipExisting() MergeOption {
+	return func(m *mergeConfig) {
+		m.skip = true
+	}
+}
+
+// OverrideExisting makes Merge replace the definition of the receiver when
+// both containers register the identity, like Override
+func OverrideExisting() MergeOption {
+	return func(m *mergeConfig) {
+		m.override = true
+	}
+}
+
+// Merge copies the definitions of other into the container, e.g. to
+// compose the containers built by plugins. The instances built by other
+// aren't copied, they are built again by the container on demand. By
+// default, nothing is merged if an identity is registered in both
+// containers and an AlreadyRegisteredError is returned.
+func (c *Container) Merge(other *Container, opts ...MergeOption) error {
+	var config mergeConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	other.RLock()
+	names := make([]Identity, 0, len(other.defs))
+	defs := make(map[Identity]definition, len(other.defs))
+	for name, def := range other.defs {
+		names = append(names, name)
+		defs[name] = *def
+	}
+	other.RUnlock()
+
+	// keep the registration order of other
+	sort.Slice(names, func(i, j int) bool { return defs[names[i]].seq < defs[names[j]].seq })
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.frozen {
+		return FrozenContainerError{Op: "merge"}
+	}
+
+	if !config.skip {
+		for _, name := range names {
+			existing, exists := c.defs[name]
+			switch {
+			case !exists:
+			case existing.sealed && config.override:
+				return SealedError{Op: "override", Name: name}
+			case !config.override:
+				return AlreadyRegisteredError{
+					Name: name,
+					msg:  fmt.Sprintf("%s was already registered", name),
+				}
+			}
+		}
+	}
+
+	// the identities of a value registered by RegisterValueAs share a new
+	// value in the container, so unregistering them in one container
+	// doesn't release the value of the other
+	shares := map[*sharedValue]*sharedValue{}
+
+	for _, name := range names {
+		def := defs[name]
+
+		if old, exists := c.defs[name]; exists {
+			if config.skip {
+				continue
+			}
+
+			delete(c.defs, name)
+			c.dropShared(name, old)
+			if retType := old.outType(); retType != nil {
+				c.typeToIdentity[retType] = pop(c.typeToIdentity[retType], name)
+			}
+			c.evict(name)
+		}
+
+		// a lazy definition which isn't provided yet is provided again
+		if def.provider != nil && def.build == nil {
+			def.providerOnce = &sync.Once{}
+			def.providerErr = nil
+		}
+
+		// like RegisterValueAs, only the first identity of a shared value
+		// is indexed by the type
+		indexed := true
+		if def.shared != nil {
+			shared, exists := shares[def.shared]
+			if !exists {
+				shared = &sharedValue{}
+				shares[def.shared] = shared
+			}
+			indexed = !exists
+			shared.refs++
+			def.shared = shared
+		}
+
+		c.seq++
+		def.seq = c.seq
+		c.defs[name] = &def
+
+		if retType := def.outType(); retType != nil && indexed {
+			c.typeToIdentity[retType] = append(c.typeToIdentity[retType], name)
+		}
+	}
+	c.resetParamCache()
+
+	return nil
+}
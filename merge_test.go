@@ -0,0 +1,127 @@
+package objectcommander
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+
+	type Mailer struct{ From string }
+
+	users := NewContainer()
+	users.Register(Identity("name"), func() string { return "users" })
+	users.Register(Identity("mailer"), func(name string) *Mailer { return &Mailer{From: name} })
+
+	orders := NewContainer()
+	orders.Register(Identity("port"), func() int { return 8080 })
+	orders.MustGet(Identity("port"))
+
+	app := NewContainer()
+	if err := app.Merge(users); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Merge(orders); err != nil {
+		t.Fatal(err)
+	}
+
+	if app.MustGet(Identity("mailer")).(*Mailer).From != "users" {
+		t.Error("failed to resolve the merged definitions")
+	}
+
+	if err := app.Invoke(func(port int) {
+		if port != 8080 {
+			t.Errorf("unexpected port: %d", port)
+		}
+	}); err != nil {
+		t.Errorf("the merged type should be resolved: %v", err)
+	}
+
+	if _, exists := app.store[Identity("port")]; !exists {
+		t.Error("the instance should be built by the receiver")
+	}
+
+	if orders.MustGet(Identity("port")) != 8080 || len(orders.defs) != 1 {
+		t.Error("the merged container should be untouched")
+	}
+}
+
+func TestMergeCollision(t *testing.T) {
+
+	base := NewContainer()
+	base.Register(Identity("name"), func() string { return "base" })
+
+	plugin := NewContainer()
+	plugin.Register(Identity("port"), func() int { return 80 })
+	plugin.Register(Identity("name"), func() string { return "plugin" })
+
+	var registered AlreadyRegisteredError
+	if err := base.Merge(plugin); !errors.As(err, &registered) || registered.Name != Identity("name") {
+		t.Errorf("should reject the collision: %v", err)
+	}
+
+	if _, err := base.Get(Identity("port")); err == nil {
+		t.Error("nothing should be merged on a collision")
+	}
+
+	if err := base.Merge(plugin, SkipExisting()); err != nil || base.MustGet(Identity("name")) != "base" {
+		t.Errorf("the existing definition should be kept: %v", err)
+	}
+
+	if err := base.Merge(plugin, OverrideExisting()); err != nil || base.MustGet(Identity("name")) != "plugin" {
+		t.Errorf("the existing definition should be overridden: %v", err)
+	}
+}
+
+func TestMergeSharedValue(t *testing.T) {
+
+	file := &memoryFile{content: "shared"}
+	fileType := reflect.TypeOf(file)
+
+	plugin := NewContainer()
+	plugin.RegisterValueAs(file, Identity("file"), Identity("backup"))
+
+	app := NewContainer()
+	if err := app.Merge(plugin); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := app.typeToIdentity[fileType]; len(ids) != 1 || ids[0] != Identity("file") {
+		t.Errorf("only the first identity of the value should be indexed: %v", ids)
+	}
+
+	app.Unregister(Identity("file"))
+	plugin.Unregister(Identity("file"))
+
+	var f *memoryFile
+	if err := plugin.Assign(&f); err != nil || f != file {
+		t.Errorf("the other identity should take over the type in the plugin: %v", err)
+	}
+	if err := app.Assign(&f); err != nil || f != file {
+		t.Errorf("the other identity should take over the type in the app: %v", err)
+	}
+}
+
+func TestMergeOverrideSharedValue(t *testing.T) {
+
+	file := &memoryFile{content: "shared"}
+
+	plugin := NewContainer()
+	plugin.Register(Identity("file"), func() string { return "plugin" })
+
+	app := NewContainer()
+	app.RegisterValueAs(file, Identity("file"), Identity("backup"))
+	if err := app.Merge(plugin, OverrideExisting()); err != nil {
+		t.Fatal(err)
+	}
+
+	var f *memoryFile
+	if err := app.Assign(&f); err != nil || f != file {
+		t.Errorf("the identity left of the overridden value should index the type: %v", err)
+	}
+
+	if got := app.MustGet(Identity("file")); got != "plugin" {
+		t.Errorf("the definition should be overridden: %v", got)
+	}
+}
@@ -0,0 +1,33 @@
+package objectcommander
+
+// SetMustPanicHandler sets the function which turns the error of the Must
+// helpers, e.g. MustGet and MustInvoke, into the value they panic with. It
+// lets the panic carry more context or be understood by a recovery
+// handler. A nil handler panics with the error itself, which is the
+// default.
+func (c *Container) SetMustPanicHandler(handler func(err error) interface{}) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.mustPanic = handler
+}
+
+// panicWith panics with the value given by the panic handler
+func (c *Container) panicWith(err error) {
+	c.RLock()
+	handler := c.mustPanic
+	c.RUnlock()
+
+	if handler == nil {
+		panic(err)
+	}
+
+	panic(handler(err))
+}
+
+// MustInvoke is Invoke which panics if there is an error
+func (c *Container) MustInvoke(function interface{}, ids ...Identity) {
+	if err := c.Invoke(function, ids...); err != nil {
+		c.panicWith(err)
+	}
+}
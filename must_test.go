@@ -0,0 +1,52 @@
+package objectcommander
+
+import (
+	"testing"
+)
+
+type httpError struct {
+	status int
+	err    error
+}
+
+func TestSetMustPanicHandler(t *testing.T) {
+
+	c := NewContainer()
+
+	recovered := func(f func()) (r interface{}) {
+		defer func() {
+			r = recover()
+		}()
+		f()
+		return nil
+	}
+
+	if r := recovered(func() { c.MustGet(Identity("missing")) }); r == nil {
+		t.Fatal("MustGet should panic")
+	} else if _, ok := r.(error); !ok {
+		t.Errorf("the default panic value should be the error: %v", r)
+	}
+
+	c.SetMustPanicHandler(func(err error) interface{} {
+		return httpError{status: 500, err: err}
+	})
+
+	for _, f := range []func(){
+		func() { c.MustGet(Identity("missing")) },
+		func() { c.MustInvoke(func(s string) {}, Identity("missing")) },
+	} {
+		r := recovered(f)
+		he, ok := r.(httpError)
+		if !ok {
+			t.Fatalf("the panic value should come from the handler: %v", r)
+		}
+
+		if he.status != 500 || he.err == nil {
+			t.Errorf("the handler should receive the error: %+v", he)
+		}
+	}
+
+	if r := recovered(func() { c.MustInvoke(func() {}) }); r != nil {
+		t.Errorf("MustInvoke shouldn't panic without an error: %v", r)
+	}
+}
@@ -0,0 +1,45 @@
+package objectcommander
+
+// Namespaced is a view of a container which prefixes every identity with
+// its namespace, so modules can use short identities without colliding
+// with each other. Resolving an identity from another namespace requires
+// the fully-qualified name on the underlying container.
+type Namespaced struct {
+	prefix    string
+	container *Container
+}
+
+// Namespace returns a view of the container whose identities are prefixed
+// with prefix + "."
+func (c *Container) Namespace(prefix string) *Namespaced {
+	return &Namespaced{
+		prefix:    prefix,
+		container: c,
+	}
+}
+
+// Identity returns the fully-qualified identity of name in the namespace
+func (n *Namespaced) Identity(name Identity) Identity {
+	return Identity(n.prefix + "." + string(name))
+}
+
+// GetContainer returns the underlying container
+func (n *Namespaced) GetContainer() *Container {
+	return n.container
+}
+
+// Register add the definition to builders under the namespace
+func (n *Namespaced) Register(name Identity, build Builder) error {
+	return n.container.Register(n.Identity(name), build)
+}
+
+// Get to get a singleton resource registered under the namespace
+func (n *Namespaced) Get(name Identity) (interface{}, error) {
+	return n.container.Get(n.Identity(name))
+}
+
+// MustGet is an helper for Get without returning error. It will panic
+// if the instance can't be resolved.
+func (n *Namespaced) MustGet(name Identity) interface{} {
+	return n.container.MustGet(n.Identity(name))
+}
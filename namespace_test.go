@@ -0,0 +1,41 @@
+package objectcommander
+
+import (
+	"testing"
+)
+
+func TestNamespaceAvoidsCollision(t *testing.T) {
+
+	c := NewContainer()
+	users := c.Namespace("users")
+	orders := c.Namespace("orders")
+
+	if err := users.Register(Identity("client"), func() string {
+		return "users client"
+	}); err != nil {
+		t.Error(err)
+	}
+
+	if err := orders.Register(Identity("client"), func() string {
+		return "orders client"
+	}); err != nil {
+		t.Errorf("namespaces should not collide: %s", err)
+	}
+
+	if users.MustGet(Identity("client")).(string) != "users client" {
+		t.Error("get the wrong instance from users namespace")
+	}
+
+	if orders.MustGet(Identity("client")).(string) != "orders client" {
+		t.Error("get the wrong instance from orders namespace")
+	}
+
+	// the fully-qualified name is required on the container
+	if _, err := c.Get(Identity("client")); err == nil {
+		t.Error("unqualified identity should not be resolved")
+	}
+
+	if c.MustGet(Identity("orders.client")).(string) != "orders client" {
+		t.Error("failed to resolve the fully-qualified identity")
+	}
+}
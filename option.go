@@ -0,0 +1,143 @@
+package objectcommander
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ContainerOption customizes the behavior of a container
+type ContainerOption func(*Container)
+
+// RegisterOption customizes how the container treats a registered definition
+type RegisterOption func(*definition)
+
+// ReturnCopy makes Get return a copy of the cached instance instead of the
+// shared one, so callers can't race on a mutable value singleton. The
+// slices, maps, arrays and structs held by the instance are copied as
+// well, while the data behind its pointers, channels, functions,
+// interfaces and unexported fields is still shared. Registering a pointer,
+// map, slice, channel, function or interface type with it fails since the
+// instance itself would be shared.
+func ReturnCopy() RegisterOption {
+	return func(d *definition) {
+		d.returnCopy = true
+	}
+}
+
+// onceType is the type of the guard injected into IdempotentStart builders
+var onceType = reflect.TypeOf(&sync.Once{})
+
+// IdempotentStart injects a *sync.Once into the builder's *sync.Once
+// parameter. The once is kept per identity for the lifetime of the
+// container, even when the instance is rebuilt by Create or unregistered,
+// so the side effects guarded by it (e.g. registering a global collector)
+// run at most once.
+func IdempotentStart() RegisterOption {
+	return func(d *definition) {
+		d.idempotentStart = true
+	}
+}
+
+// startOnce returns the once guard of the identity
+func (c *Container) startOnce(name Identity) *sync.Once {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.onces == nil {
+		c.onces = make(map[Identity]*sync.Once)
+	}
+
+	once, exists := c.onces[name]
+	if !exists {
+		once = &sync.Once{}
+		c.onces[name] = once
+	}
+
+	return once
+}
+
+// outType returns the type of the instance built by the definition. It's
+// nil if the builder isn't provided yet.
+func (d *definition) outType() reflect.Type {
+	if d.build == nil {
+		return nil
+	}
+
+	ftype := reflect.TypeOf(d.build)
+	valueIndex, _, _ := builderOutputs(ftype)
+
+	return ftype.Out(valueIndex)
+}
+
+// checkCopy returns an error if ReturnCopy can't copy the instances of
+// the definition
+func (d *definition) checkCopy() error {
+	if !d.returnCopy {
+		return nil
+	}
+
+	t := d.outType()
+	if t == nil {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func, reflect.Interface, reflect.UnsafePointer:
+		return fmt.Errorf("ReturnCopy can't copy the instances of %s which share their data", t)
+	}
+
+	return nil
+}
+
+// instance returns the value handed out to the callers for the cached obj
+func (d *definition) instance(obj interface{}) interface{} {
+	if d == nil || !d.returnCopy || obj == nil {
+		return obj
+	}
+
+	return deepCopy(reflect.ValueOf(obj)).Interface()
+}
+
+// deepCopy copies the value along with the slices, maps, arrays and
+// structs it holds, see ReturnCopy
+func deepCopy(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Cap())
+		for i := 0; i < value.Len(); i++ {
+			copied.Index(i).Set(deepCopy(value.Index(i)))
+		}
+		return copied
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return copied
+	case reflect.Array:
+		copied := reflect.New(value.Type()).Elem()
+		for i := 0; i < value.Len(); i++ {
+			copied.Index(i).Set(deepCopy(value.Index(i)))
+		}
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(value.Type()).Elem()
+		copied.Set(value)
+		for i := 0; i < value.NumField(); i++ {
+			if field := copied.Field(i); field.CanSet() {
+				field.Set(deepCopy(value.Field(i)))
+			}
+		}
+		return copied
+	}
+
+	return value
+}
@@ -0,0 +1,75 @@
+package objectcommander
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestReturnCopy(t *testing.T) {
+
+	type Settings struct {
+		Retries int
+		Hosts   []string
+		Labels  map[string]string
+	}
+
+	c := NewContainer()
+	c.Register(Identity("settings"), func() Settings {
+		return Settings{Retries: 3, Hosts: []string{"a", "b"}, Labels: map[string]string{"env": "prod"}}
+	}, ReturnCopy())
+
+	settings := c.MustGet(Identity("settings")).(Settings)
+	settings.Retries = 10
+	settings.Hosts[0] = "z"
+	settings.Labels["env"] = "dev"
+
+	original := c.MustGet(Identity("settings")).(Settings)
+	if original.Retries != 3 || original.Hosts[0] != "a" || original.Labels["env"] != "prod" {
+		t.Errorf("mutating the copy should not affect the cached instance: %+v", original)
+	}
+
+	type Pool struct{ Size int }
+	err := c.Register(Identity("pool"), func() *Pool {
+		return &Pool{Size: 1}
+	}, ReturnCopy())
+	if err == nil {
+		t.Error("should reject a pointer which can't be copied")
+	}
+
+	if _, exists := c.defs[Identity("pool")]; exists {
+		t.Error("the rejected definition should not be registered")
+	}
+}
+
+func TestIdempotentStart(t *testing.T) {
+
+	type Collector struct{ Name string }
+
+	registered := 0
+	c := NewContainer()
+	c.Register(Identity("collector"), func(once *sync.Once) *Collector {
+		once.Do(func() {
+			// mimic registering to a global registry
+			registered++
+		})
+		return &Collector{Name: "requests"}
+	}, IdempotentStart())
+
+	first, err := c.Create(Identity("collector"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := c.Create(Identity("collector"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first == second {
+		t.Error("the instance should still be rebuilt")
+	}
+
+	if registered != 1 {
+		t.Errorf("the guarded section should run once but run %d times", registered)
+	}
+}
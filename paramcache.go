@@ -0,0 +1,49 @@
+package objectcommander
+
+import (
+	"reflect"
+)
+
+// paramCache keeps the identities which the params of a function resolve
+// to by their types, so repeated calls of the same function skip the type
+// lookups. It's reset whenever the type index changes.
+type paramCache struct {
+	params map[reflect.Type][]Identity
+	gen    uint64 // gen is bumped on every reset to drop stale results
+}
+
+// cachedParams returns the cached identities of the params of fn and the
+// generation of the cache. The cache is disabled for a child container
+// since the parent may register a type it resolves at any time.
+func (c *Container) cachedParams(fn reflect.Type) ([]Identity, uint64) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.parent != nil {
+		return nil, 0
+	}
+
+	return c.paramCache.params[fn], c.paramCache.gen
+}
+
+// cacheParams stores the identities resolved for the params of fn unless
+// the type index changed since gen
+func (c *Container) cacheParams(fn reflect.Type, ids []Identity, gen uint64) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.parent != nil || c.paramCache.gen != gen {
+		return
+	}
+
+	if c.paramCache.params == nil {
+		c.paramCache.params = make(map[reflect.Type][]Identity)
+	}
+	c.paramCache.params[fn] = ids
+}
+
+// resetParamCache drops the cached identities, the caller holds the lock
+func (c *Container) resetParamCache() {
+	c.paramCache.params = nil
+	c.paramCache.gen++
+}
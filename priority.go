@@ -0,0 +1,137 @@
+package objectcommander
+
+import (
+	"reflect"
+	"sort"
+)
+
+// WithPriority sets the priority of the definition. When several
+// definitions share a type or a tag, the one with a higher priority comes
+// first and it's the one GetByType resolves. The default priority is 0.
+func WithPriority(priority int) RegisterOption {
+	return func(d *definition) {
+		d.priority = priority
+	}
+}
+
+// BuildOrder sets the order in which Warm and WarmAll of the bootstrap
+// build the definition, the lower order is built first. It only
+// breaks the ties among the independent definitions, a definition is
+// still built after the ones it depends on. The default order is 0 and
+// the definitions of the same order are built in the order of the
+// registration.
+func BuildOrder(order int) RegisterOption {
+	return func(d *definition) {
+		d.buildOrder = order
+	}
+}
+
+// WithTags tags the definition so it can be collected by GetByTag
+func WithTags(tags ...string) RegisterOption {
+	return func(d *definition) {
+		d.tags = append(d.tags, tags...)
+	}
+}
+
+// hasTag reports whether the definition is tagged with the tag
+func (d *definition) hasTag(tag string) bool {
+	for _, t := range d.tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetByTag returns every instance tagged with the tag ordered by the
+// priority and then the order of registration
+func (c *Container) GetByTag(tag string) ([]interface{}, error) {
+	c.RLock()
+	ids := []Identity{}
+	for name, def := range c.defs {
+		if def.hasTag(tag) {
+			ids = append(ids, name)
+		}
+	}
+	ids = c.sortByPriority(ids)
+	c.RUnlock()
+
+	results := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		result, err := c.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// SetDefaultIdentity makes the identity the one resolving the type, e.g.
+// "postgres" for a Store, whatever the priority and the order of
+// registration are. It applies to GetByType, Assign, Invoke and the params
+// of the builders. The default is ignored while the identity isn't
+// registered with the type.
+func (c *Container) SetDefaultIdentity(t reflect.Type, id Identity) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.defaults == nil {
+		c.defaults = make(map[reflect.Type]Identity)
+	}
+	c.defaults[t] = id
+	c.resetParamCache()
+}
+
+// ResolutionStrategy chooses the identity which resolves the type among
+// the candidates, which are ordered by the priority and then the order of
+// registration. It may return an error, e.g. to reject an ambiguous type.
+type ResolutionStrategy func(t reflect.Type, candidates []Identity) (Identity, error)
+
+// SetResolutionStrategy replaces how a type is resolved when several
+// identities are registered with it, for GetByType, Assign, Invoke and the
+// params of the builders. The strategy is only consulted when there is
+// more than one candidate, and it takes the place of the default
+// identities and the priorities. A nil strategy restores the default,
+// which chooses the default identity, see SetDefaultIdentity, and then
+// the first candidate. The choice for the params of a function is cached,
+// so the strategy should choose the same identity for the same candidates.
+func (c *Container) SetResolutionStrategy(strategy ResolutionStrategy) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.strategy = strategy
+	c.resetParamCache()
+}
+
+// sortByPriority sorts the identities by the priority in the descending
+// order and then the order of registration. The caller must hold the lock.
+func (c *Container) sortByPriority(ids []Identity) []Identity {
+	sort.SliceStable(ids, func(i, j int) bool {
+		left, right := c.defs[ids[i]], c.defs[ids[j]]
+		if left.priority != right.priority {
+			return left.priority > right.priority
+		}
+
+		return left.seq < right.seq
+	})
+
+	return ids
+}
+
+// FlushByTag drops the cached instances of the definitions tagged with
+// tag, e.g. every "cache" singleton during a cache clear. The definitions
+// are kept so the instances are built again on demand. Like Invalidate,
+// the instances are dropped without being closed.
+func (c *Container) FlushByTag(tag string) {
+	c.Lock()
+	defer c.Unlock()
+
+	for name, def := range c.defs {
+		if def.hasTag(tag) {
+			c.invalidate(name)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package objectcommander
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestPriority(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("logging"), func() testHandler { return routeHandler("logging") }, WithTags("middleware"))
+	c.Register(Identity("auth"), func() testHandler { return routeHandler("auth") }, WithTags("middleware"), WithPriority(10))
+	c.Register(Identity("metrics"), func() testHandler { return routeHandler("metrics") }, WithTags("middleware"))
+	c.Register(Identity("recovery"), func() string { return "recovery" }, WithTags("middleware"), WithPriority(20))
+
+	handlers, err := c.GetAllByType(reflect.TypeOf((*testHandler)(nil)).Elem())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := []string{}
+	for _, h := range handlers {
+		routes = append(routes, h.(testHandler).Route())
+	}
+
+	if !reflect.DeepEqual(routes, []string{"auth", "logging", "metrics"}) {
+		t.Errorf("unexpected order: %v", routes)
+	}
+
+	tagged, err := c.GetByTag("middleware")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tagged) != 4 || tagged[0] != "recovery" || tagged[1].(testHandler).Route() != "auth" {
+		t.Errorf("unexpected order of the tagged instances: %v", tagged)
+	}
+
+	var primary testHandler
+	if err := c.Assign(&primary); err != nil || primary.Route() != "auth" {
+		t.Error("the highest priority should be resolved by type")
+	}
+}
+
+func TestFlushByTag(t *testing.T) {
+
+	type Cache struct{ Name string }
+
+	c := NewContainer()
+	c.Register(Identity("users"), func() *Cache { return &Cache{Name: "users"} }, WithTags("cache"))
+	c.Register(Identity("orders"), func() *Cache { return &Cache{Name: "orders"} }, WithTags("cache"))
+	c.Register(Identity("db"), func() *Cache { return &Cache{Name: "db"} }, WithTags("storage"))
+
+	users := c.MustGet(Identity("users"))
+	c.MustGet(Identity("orders"))
+	db := c.MustGet(Identity("db"))
+
+	c.FlushByTag("cache")
+
+	if _, exists := c.store[Identity("orders")]; exists {
+		t.Error("the tagged instances should be flushed")
+	}
+
+	if c.MustGet(Identity("users")) == users {
+		t.Error("the flushed instance should be built again")
+	}
+
+	if c.MustGet(Identity("db")) != db {
+		t.Error("the instances of other tags should be kept")
+	}
+}
+
+func TestSetDefaultIdentity(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("memory"), func() testHandler { return routeHandler("memory") })
+	c.Register(Identity("postgres"), func() testHandler { return routeHandler("postgres") })
+	c.Register(Identity("redis"), func() testHandler { return routeHandler("redis") }, WithPriority(10))
+
+	route := func() string {
+		var routed string
+		if err := c.Invoke(func(h testHandler) { routed = h.Route() }); err != nil {
+			t.Fatal(err)
+		}
+		return routed
+	}
+
+	if route() != "redis" {
+		t.Fatal("the highest priority should win without a default")
+	}
+
+	handlerType := reflect.TypeOf((*testHandler)(nil)).Elem()
+	c.SetDefaultIdentity(handlerType, Identity("postgres"))
+
+	if route() != "postgres" {
+		t.Error("the default identity should win over the priority and the order of registration")
+	}
+
+	var h testHandler
+	if err := c.Assign(&h); err != nil || h.Route() != "postgres" {
+		t.Error("Assign should resolve the default identity")
+	}
+
+	c.Unregister(Identity("postgres"))
+	if result, _ := c.GetByType(handlerType); result.(testHandler).Route() != "redis" {
+		t.Error("an unregistered default should be ignored")
+	}
+}
+
+func TestSetResolutionStrategy(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("b-store"), func() testHandler { return routeHandler("b-store") })
+	c.Register(Identity("c-store"), func() testHandler { return routeHandler("c-store") })
+	c.Register(Identity("a-store"), func() testHandler { return routeHandler("a-store") }, WithPriority(10))
+	c.Register(Identity("port"), func() int { return 80 })
+
+	consulted := 0
+	c.SetResolutionStrategy(func(t reflect.Type, candidates []Identity) (Identity, error) {
+		consulted++
+		last := candidates[0]
+		for _, id := range candidates[1:] {
+			if id > last {
+				last = id
+			}
+		}
+		return last, nil
+	})
+
+	var routed string
+	if err := c.Invoke(func(h testHandler, port int) { routed = h.Route() }); err != nil {
+		t.Fatal(err)
+	}
+
+	if routed != "c-store" {
+		t.Errorf("the strategy should pick the lexicographically-last identity: %s", routed)
+	}
+
+	if consulted != 1 {
+		t.Errorf("the strategy should only be consulted for several candidates: %d", consulted)
+	}
+
+	c.SetResolutionStrategy(func(t reflect.Type, candidates []Identity) (Identity, error) {
+		return "", fmt.Errorf("%s is ambiguous: %v", t, candidates)
+	})
+
+	if _, err := c.GetByType(reflect.TypeOf((*testHandler)(nil)).Elem()); err == nil {
+		t.Error("the error of the strategy should be returned")
+	}
+
+	c.SetResolutionStrategy(nil)
+	if result, _ := c.GetByType(reflect.TypeOf((*testHandler)(nil)).Elem()); result.(testHandler).Route() != "a-store" {
+		t.Error("a nil strategy should restore the default")
+	}
+}
@@ -0,0 +1,66 @@
+package objectcommander
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ResolutionRecord is a resolution recorded by RecordResolutions
+type ResolutionRecord struct {
+	Name     Identity
+	Type     reflect.Type  // Type is the type of the instance, it's nil if the resolution fails
+	Err      error         // Err is the error of the resolution
+	Started  time.Time     // Started is when the resolution began
+	Duration time.Duration // Duration includes the resolution of the dependencies
+}
+
+// recorder collects the resolutions while RecordResolutions is on
+type recorder struct {
+	records []ResolutionRecord
+	sync.Mutex
+}
+
+// record resolves the instance by resolve and records the resolution
+func (r *recorder) record(name Identity, resolve func() (interface{}, error)) (interface{}, error) {
+	started := time.Now()
+	obj, err := resolve()
+
+	record := ResolutionRecord{Name: name, Err: err, Started: started, Duration: time.Since(started)}
+	if obj != nil {
+		record.Type = reflect.TypeOf(obj)
+	}
+
+	r.Lock()
+	r.records = append(r.records, record)
+	r.Unlock()
+
+	return obj, err
+}
+
+// recording returns the recorder, it's nil if nothing is being recorded
+func (c *Container) recording() *recorder {
+	rec, _ := c.recorder.Load().(*recorder)
+	return rec
+}
+
+// RecordResolutions starts recording every resolution of the container,
+// both Get and the dependencies resolved for a builder, to reproduce what
+// the container did, e.g. during a failing startup. The returned function
+// stops the recording and returns the records in the order the
+// resolutions finished, so a dependency comes before the instance built
+// from it. The resolutions of the parent of a child container aren't
+// recorded.
+func (c *Container) RecordResolutions() func() []ResolutionRecord {
+	rec := &recorder{}
+	c.recorder.Store(rec)
+
+	return func() []ResolutionRecord {
+		c.recorder.CompareAndSwap(rec, (*recorder)(nil))
+
+		rec.Lock()
+		defer rec.Unlock()
+
+		return append([]ResolutionRecord(nil), rec.records...)
+	}
+}
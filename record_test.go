@@ -0,0 +1,48 @@
+package objectcommander
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRecordResolutions(t *testing.T) {
+
+	type Config struct{ DSN string }
+	type DB struct{ Config *Config }
+
+	c := NewContainer()
+	c.Register(Identity("config"), func() *Config { return &Config{DSN: "postgres://"} })
+	c.Register(Identity("db"), func(config *Config) *DB { return &DB{Config: config} })
+	c.Register(Identity("cache"), func() (string, error) { return "", errors.New("refused") })
+
+	c.MustGet(Identity("config"))
+
+	stop := c.RecordResolutions()
+	c.MustGet(Identity("db"))
+	c.MustGet(Identity("db"))
+	c.Get(Identity("cache"))
+	records := stop()
+
+	names := []Identity{}
+	for _, r := range records {
+		names = append(names, r.Name)
+	}
+
+	if !reflect.DeepEqual(names, []Identity{"config", "db", "db", "cache"}) {
+		t.Fatalf("unexpected records: %v", names)
+	}
+
+	if records[1].Type != reflect.TypeOf(&DB{}) || records[1].Err != nil || records[1].Started.IsZero() {
+		t.Errorf("unexpected record of db: %+v", records[1])
+	}
+
+	if records[3].Type != nil || records[3].Err == nil {
+		t.Errorf("the failure of cache should be recorded: %+v", records[3])
+	}
+
+	c.MustGet(Identity("db"))
+	if len(stop()) != len(records) {
+		t.Error("nothing should be recorded once stopped")
+	}
+}
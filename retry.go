@@ -0,0 +1,59 @@
+package objectcommander
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// retryPolicy is how many times a failing builder is called and how long
+// to wait between the attempts
+type retryPolicy struct {
+	attempts int
+	backoff  time.Duration
+}
+
+// RegisterWithRetry is Register with a builder which is called up to
+// attempts times until it succeeds, waiting backoff between the attempts,
+// e.g. for a database which is still starting. The last error is returned
+// if every attempt fails. The retries stop once the context of every
+// GetCtx waiting for the instance is done. A builder which panics isn't
+// retried.
+func (c *Container) RegisterWithRetry(name Identity, build Builder, attempts int, backoff time.Duration, opts ...RegisterOption) error {
+	if attempts < 1 {
+		return fmt.Errorf("%s should be attempted at least once instead of %d", name, attempts)
+	}
+
+	return c.Register(name, build, append(opts, func(d *definition) {
+		d.retry = retryPolicy{attempts: attempts, backoff: backoff}
+	})...)
+}
+
+// invokeWithRetry calls the builder and returns its error, the builder is
+// called again by the retry policy of the definition
+func (c *Container) invokeWithRetry(res *resolution, name Identity, def *definition, fn reflect.Value, args []reflect.Value, errIndex int) ([]reflect.Value, error) {
+	ctx := res.callerCtx()
+
+	for attempt := 1; ; attempt++ {
+		ret, err := c.invokeBuilder(name, fn, args)
+		if err == nil && errIndex >= 0 {
+			if buildErr := errorOf(ret[errIndex]); buildErr != nil {
+				err = fmt.Errorf("failed to build %s: %w", name, buildErr)
+			}
+		}
+
+		if err == nil || attempt >= def.retry.attempts {
+			return ret, err
+		}
+
+		c.logf("attempt %d of %d to build %s failed, retrying in %s: %s", attempt, def.retry.attempts, name, def.retry.backoff, err)
+
+		timer := time.NewTimer(def.retry.backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("stopped retrying %s: %w", name, ctx.Err())
+		}
+	}
+}
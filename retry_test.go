@@ -0,0 +1,124 @@
+package objectcommander
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegisterWithRetry(t *testing.T) {
+
+	type DB struct{ Attempts int }
+
+	attempts := 0
+	c := NewContainer()
+	c.RegisterWithRetry(Identity("db"), func() (*DB, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("connection refused")
+		}
+		return &DB{Attempts: attempts}, nil
+	}, 3, time.Millisecond)
+
+	db, err := c.Get(Identity("db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if db.(*DB).Attempts != 3 {
+		t.Errorf("should succeed at the third attempt: %+v", db)
+	}
+
+	failures := 0
+	c.RegisterWithRetry(Identity("cache"), func() (string, error) {
+		failures++
+		return "", errors.New("connection refused")
+	}, 2, time.Millisecond)
+
+	if _, err := c.Get(Identity("cache")); err == nil || failures != 2 {
+		t.Errorf("should return the last error after every attempt: %d %v", failures, err)
+	}
+
+	if err := c.RegisterWithRetry(Identity("queue"), func() int { return 0 }, 0, 0); err == nil {
+		t.Error("should be attempted at least once")
+	}
+}
+
+func TestRegisterWithRetryDeadline(t *testing.T) {
+
+	var attempts int32
+	c := NewContainer()
+	c.RegisterWithRetry(Identity("db"), func() (string, error) {
+		atomic.AddInt32(&attempts, 1)
+		return "", errors.New("connection refused")
+	}, 1000, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.GetCtx(ctx, Identity("db")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("should stop at the deadline: %v", err)
+	}
+
+	// the build is detached from the caller but its retries stop once
+	// nobody waits for it
+	time.Sleep(30 * time.Millisecond)
+	stopped := atomic.LoadInt32(&attempts)
+	time.Sleep(50 * time.Millisecond)
+
+	if atomic.LoadInt32(&attempts) != stopped {
+		t.Error("the retries should stop once the context is done")
+	}
+}
+
+func TestRegisterWithRetryWaiters(t *testing.T) {
+
+	var attempts int32
+	c := NewContainer()
+	c.RegisterWithRetry(Identity("db"), func() (string, error) {
+		atomic.AddInt32(&attempts, 1)
+		return "", errors.New("connection refused")
+	}, 1000, 10*time.Millisecond)
+
+	short, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	long, cancelLong := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelLong()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.GetCtx(short, Identity("db"))
+		done <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	go func() {
+		_, err := c.GetCtx(long, Identity("db"))
+		done <- err
+	}()
+
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("the first caller should stop at its deadline: %v", err)
+	}
+
+	// the second caller still waits, so the build keeps retrying for it
+	time.Sleep(10 * time.Millisecond)
+	retrying := atomic.LoadInt32(&attempts)
+	time.Sleep(30 * time.Millisecond)
+	if atomic.LoadInt32(&attempts) == retrying {
+		t.Error("the retries should go on while a caller still waits")
+	}
+
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("the second caller should stop at its deadline: %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	stopped := atomic.LoadInt32(&attempts)
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt32(&attempts) != stopped {
+		t.Error("the retries should stop once every caller is gone")
+	}
+}
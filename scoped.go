@@ -0,0 +1,37 @@
+package objectcommander
+
+import (
+	"context"
+)
+
+// RegisterScoped registers a builder whose instances are cached by the key
+// which keyFn takes from the context, e.g. one cache per tenant.
+// GetCtx(ctx, name) returns the instance of the key of ctx, and the
+// dependencies are resolved by the key of the context which resolves
+// them. Get uses context.Background(). The closer of the definition runs
+// for every scoped instance when the container is closed.
+func (c *Container) RegisterScoped(name Identity, keyFn func(ctx context.Context) string, build Builder, opts ...RegisterOption) error {
+	return c.Register(name, build, append(opts, func(d *definition) {
+		d.scopeKey = keyFn
+	})...)
+}
+
+// getScoped returns the instance of the key of the caller's context or
+// builds it. Like a singleton, the concurrent calls with the same key
+// share one build.
+func (c *Container) getScoped(res *resolution, name Identity, def *definition) (interface{}, error) {
+	return c.join(res, flightKey{name: name, scope: def.scopeKey(res.callerCtx()), scoped: true})
+}
+
+// putScoped stores the instance of the scope and keeps its closer and the
+// cleanup of its builder to run on Close. The caller holds the lock.
+func (c *Container) putScoped(key flightKey, obj interface{}, cleanup func()) {
+	if c.scopedMemo == nil {
+		c.scopedMemo = make(map[Identity]map[string]interface{})
+	}
+	if c.scopedMemo[key.name] == nil {
+		c.scopedMemo[key.name] = make(map[string]interface{})
+	}
+	c.scopedMemo[key.name][key.scope] = obj
+	c.scoped = append(c.scoped, c.closingsOf(key.name, obj, cleanup)...)
+}
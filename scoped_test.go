@@ -0,0 +1,115 @@
+package objectcommander
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type tenantKey struct{}
+
+func tenantOf(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+func TestRegisterScoped(t *testing.T) {
+
+	type Cache struct{ Tenant string }
+	type Service struct{ Cache *Cache }
+
+	closed := []string{}
+	c := NewContainer()
+	c.RegisterScoped(Identity("cache"), tenantOf, func() *Cache {
+		return &Cache{}
+	}, WithCloser(func(v interface{}) error {
+		closed = append(closed, "cache")
+		return nil
+	}))
+	c.RegisterScoped(Identity("service"), tenantOf, func(cache *Cache) *Service {
+		return &Service{Cache: cache}
+	})
+
+	acme := context.WithValue(context.Background(), tenantKey{}, "acme")
+	globex := context.WithValue(context.Background(), tenantKey{}, "globex")
+
+	first, err := c.GetCtx(acme, Identity("cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if again, _ := c.GetCtx(acme, Identity("cache")); again != first {
+		t.Error("the same key should return the same instance")
+	}
+
+	other, _ := c.GetCtx(globex, Identity("cache"))
+	if other == first {
+		t.Error("different keys should return different instances")
+	}
+
+	service, _ := c.GetCtx(globex, Identity("service"))
+	if service.(*Service).Cache != other {
+		t.Error("the dependencies should be resolved by the same key")
+	}
+
+	c.Close()
+	if len(closed) != 2 {
+		t.Errorf("every scoped instance should be closed: %v", closed)
+	}
+
+	if rebuilt, _ := c.GetCtx(acme, Identity("cache")); rebuilt == first {
+		t.Error("the scoped instances should be dropped once closed")
+	}
+}
+
+func TestRegisterScopedConcurrent(t *testing.T) {
+
+	type Cache struct{}
+
+	var builds int32
+	c := NewContainer()
+	c.RegisterScoped(Identity("cache"), tenantOf, func() *Cache {
+		atomic.AddInt32(&builds, 1)
+		time.Sleep(10 * time.Millisecond)
+		return &Cache{}
+	})
+
+	acme := context.WithValue(context.Background(), tenantKey{}, "acme")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.GetCtx(acme, Identity("cache"))
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&builds); n != 1 {
+		t.Errorf("the concurrent calls with the same key should share one build: %d", n)
+	}
+}
+
+func TestRegisterScopedWarmup(t *testing.T) {
+
+	type Cache struct{ Warm bool }
+
+	c := NewContainer()
+	c.RegisterScoped(Identity("cache"), tenantOf, func() *Cache {
+		return &Cache{}
+	}, func(d *definition) {
+		d.warmup = func(v interface{}) error {
+			v.(*Cache).Warm = true
+			return nil
+		}
+	})
+
+	acme := context.WithValue(context.Background(), tenantKey{}, "acme")
+	cache, err := c.GetCtx(acme, Identity("cache"))
+	if err != nil || !cache.(*Cache).Warm {
+		t.Errorf("the scoped instance should be warmed up: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package objectcommander
+
+import (
+	"reflect"
+)
+
+// Snapshot is the state of the definitions of a container at some point,
+// see Container.Snapshot
+type Snapshot struct {
+	defs           map[Identity]*definition
+	typeToIdentity map[reflect.Type][]Identity
+	seq            uint64
+}
+
+// Snapshot records the definitions of the container so they can be
+// restored later, e.g. to undo the overrides of a test.
+func (c *Container) Snapshot() *Snapshot {
+	c.RLock()
+	defer c.RUnlock()
+
+	return &Snapshot{
+		defs:           copyDefs(c.defs),
+		typeToIdentity: copyTypeIndex(c.typeToIdentity),
+		seq:            c.seq,
+	}
+}
+
+// Restore brings the definitions back to the snapshot. The instances of
+// the definitions which were changed or registered since the snapshot are
+// dropped without being closed, the others are kept. The sealed
+// definitions are kept as they are.
+func (c *Container) Restore(s *Snapshot) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.frozen {
+		return FrozenContainerError{Op: "restore"}
+	}
+
+	for name, def := range c.defs {
+		if def != s.defs[name] && !def.sealed {
+			c.evict(name)
+		}
+	}
+
+	defs := copyDefs(s.defs)
+	typeToIdentity := copyTypeIndex(s.typeToIdentity)
+
+	// the sealed definitions registered since the snapshot are kept
+	for name, def := range c.defs {
+		if !def.sealed || defs[name] == def {
+			continue
+		}
+
+		if old, exists := defs[name]; exists {
+			if retType := old.outType(); retType != nil {
+				typeToIdentity[retType] = pop(typeToIdentity[retType], name)
+			}
+		}
+		defs[name] = def
+		retType := def.outType()
+		typeToIdentity[retType] = append(typeToIdentity[retType], name)
+	}
+
+	c.defs = defs
+	c.typeToIdentity = typeToIdentity
+	if c.seq < s.seq {
+		c.seq = s.seq
+	}
+	c.resetParamCache()
+
+	return nil
+}
+
+func copyDefs(defs map[Identity]*definition) map[Identity]*definition {
+	copied := make(map[Identity]*definition, len(defs))
+	for name, def := range defs {
+		copied[name] = def
+	}
+
+	return copied
+}
+
+// copyTypeIndex copies the slices as well, otherwise appending to a slice
+// of one index could write to the backing array shared with the other
+func copyTypeIndex(index map[reflect.Type][]Identity) map[reflect.Type][]Identity {
+	copied := make(map[reflect.Type][]Identity, len(index))
+	for t, ids := range index {
+		copied[t] = append([]Identity(nil), ids...)
+	}
+
+	return copied
+}
@@ -0,0 +1,67 @@
+package objectcommander
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("primary"), func() string { return "primary" })
+	c.Register(Identity("replica"), func() string { return "replica" })
+	c.Register(Identity("port"), func() int { return 80 })
+	port := c.MustGet(Identity("port"))
+	c.MustGet(Identity("primary"))
+
+	snapshot := c.Snapshot()
+
+	c.Register(Identity("fake"), func() string { return "fake" }, WithPriority(1))
+	c.Override(Identity("primary"), func() string { return "overridden" })
+
+	if err := c.Restore(snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Get(Identity("fake")); err == nil {
+		t.Error("the definition registered after the snapshot should be gone")
+	}
+
+	if ids := c.typeToIdentity[typeOf[string]()]; !reflect.DeepEqual(ids, []Identity{"primary", "replica"}) {
+		t.Errorf("the type index should match the snapshot: %v", ids)
+	}
+
+	if err := c.Invoke(func(name string) {
+		if name != "primary" {
+			t.Errorf("the type should resolve like the snapshot: %s", name)
+		}
+	}); err != nil {
+		t.Error(err)
+	}
+
+	if c.MustGet(Identity("port")) != port {
+		t.Error("the unchanged instance should be kept")
+	}
+}
+
+func TestSnapshotAliasing(t *testing.T) {
+
+	c := NewContainer()
+	c.Register(Identity("a"), func() string { return "a" })
+	c.Register(Identity("b"), func() string { return "b" })
+	c.Register(Identity("c"), func() string { return "c" })
+
+	// the slice of strings has a spare capacity now, the registrations
+	// below would write to the same backing array if it was shared
+	base := c.Snapshot()
+	c.Register(Identity("first"), func() string { return "first" })
+	withFirst := c.Snapshot()
+
+	c.Restore(base)
+	c.Register(Identity("second"), func() string { return "second" })
+
+	c.Restore(withFirst)
+	if ids := c.typeToIdentity[typeOf[string]()]; !reflect.DeepEqual(ids, []Identity{"a", "b", "c", "first"}) {
+		t.Errorf("the snapshot should not be changed by a later registration: %v", ids)
+	}
+}
@@ -0,0 +1,69 @@
+package objectcommander
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// BuildTimeoutError is returned when a builder runs longer than the build
+// timeout of the container
+type BuildTimeoutError struct {
+	Name    Identity
+	Timeout time.Duration
+}
+
+// Error returns the error message
+func (b BuildTimeoutError) Error() string {
+	return fmt.Sprintf("building %s took longer than %s", b.Name, b.Timeout)
+}
+
+// SetBuildTimeout limits how long every builder can run. Zero means there
+// is no limit, which is the default.
+//
+// A builder which exceeds the timeout keeps running in its own goroutine
+// because Go can't stop it, so a builder which never returns leaks the
+// goroutine. Its result is dropped once it returns.
+func (c *Container) SetBuildTimeout(timeout time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.buildTimeout = timeout
+}
+
+// invokeBuilder calls the builder within the build timeout
+func (c *Container) invokeBuilder(name Identity, fn reflect.Value, args []reflect.Value) ([]reflect.Value, error) {
+	c.RLock()
+	timeout := c.buildTimeout
+	c.RUnlock()
+
+	if timeout <= 0 {
+		return invoker(fn, args), nil
+	}
+
+	done := make(chan []reflect.Value, 1)
+	panicked := make(chan interface{}, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				panicked <- r
+			}
+		}()
+
+		done <- invoker(fn, args)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case ret := <-done:
+		return ret, nil
+	case r := <-panicked:
+		// keep the behavior of a builder panicking without the timeout
+		panic(r)
+	case <-timer.C:
+		return nil, BuildTimeoutError{Name: name, Timeout: timeout}
+	}
+}
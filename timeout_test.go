@@ -0,0 +1,36 @@
+package objectcommander
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBuildTimeout(t *testing.T) {
+
+	c := NewContainer()
+	c.SetBuildTimeout(20 * time.Millisecond)
+
+	c.Register(Identity("slow"), func() string {
+		time.Sleep(200 * time.Millisecond)
+		return "slow"
+	})
+	c.Register(Identity("fast"), func() string {
+		return "fast"
+	})
+
+	_, err := c.Get(Identity("slow"))
+
+	var timeout BuildTimeoutError
+	if !errors.As(err, &timeout) || timeout.Name != Identity("slow") {
+		t.Errorf("expected a build timeout error but get %v", err)
+	}
+
+	if _, err := c.Get(Identity("slow")); err == nil {
+		t.Error("a timed out instance should not be cached")
+	}
+
+	if c.MustGet(Identity("fast")).(string) != "fast" {
+		t.Error("a fast builder should not be affected")
+	}
+}
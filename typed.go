@@ -0,0 +1,138 @@
+package objectcommander
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// typedBuild builds the instance without reflection, see RegisterFunc0
+type typedBuild func(c *Container, res *resolution) (interface{}, error)
+
+// withTypedBuild sets the typed build of the definition unless one of the
+// params needs the reflection path, e.g. a Lazy[T] or a Deps struct
+func withTypedBuild(build typedBuild, params ...reflect.Type) RegisterOption {
+	return func(d *definition) {
+		for _, t := range params {
+			if _, isLazy := lazyElem(t); isLazy || isDeps(t) || t == onceType {
+				return
+			}
+		}
+		d.typed = build
+	}
+}
+
+// resolveParam resolves a param of the typed build by its type
+func resolveParam[D any](c *Container, res *resolution) (D, error) {
+	var zero D
+	t := typeOf[D]()
+
+	id, exists, err := c.lookup(t)
+	if err != nil {
+		return zero, err
+	}
+
+	if !exists {
+		converted, convertible, err := c.fallback(res, t)
+		if !convertible {
+			return zero, fmt.Errorf("there is no instance registered with type: %s", t)
+		}
+		if err != nil || !converted.IsValid() {
+			return zero, err
+		}
+		// a converter may return nil for an interface
+		typed, _ := converted.Interface().(D)
+		return typed, nil
+	}
+
+	obj, err := c.get(res, id)
+	if err != nil || obj == nil {
+		return zero, err
+	}
+
+	typed, ok := obj.(D)
+	if !ok {
+		return zero, fmt.Errorf("the instance of %s is a %T", t, obj)
+	}
+
+	return typed, nil
+}
+
+// RegisterFunc0 registers a typed builder which is called without
+// reflection. It's faster than Register for the hot paths, e.g. a
+// transient built by Create on every request, and behaves the same. The
+// reflection path is still used with a build timeout or BeforeBuild hooks.
+func RegisterFunc0[T any](c *Container, name Identity, build func() T, opts ...RegisterOption) error {
+	return c.Register(name, build, append(opts, withTypedBuild(func(*Container, *resolution) (interface{}, error) {
+		return build(), nil
+	}))...)
+}
+
+// RegisterFunc1 is RegisterFunc0 for a builder taking one dependency
+// resolved by its type
+func RegisterFunc1[T, D1 any](c *Container, name Identity, build func(D1) T, opts ...RegisterOption) error {
+	return c.Register(name, build, append(opts, withTypedBuild(func(c *Container, res *resolution) (interface{}, error) {
+		d1, err := resolveParam[D1](c, res)
+		if err != nil {
+			return nil, err
+		}
+
+		return build(d1), nil
+	}, typeOf[D1]()))...)
+}
+
+// RegisterFunc2 is RegisterFunc0 for a builder taking two dependencies
+// resolved by their types
+func RegisterFunc2[T, D1, D2 any](c *Container, name Identity, build func(D1, D2) T, opts ...RegisterOption) error {
+	return c.Register(name, build, append(opts, withTypedBuild(func(c *Container, res *resolution) (interface{}, error) {
+		d1, err := resolveParam[D1](c, res)
+		if err != nil {
+			return nil, err
+		}
+
+		d2, err := resolveParam[D2](c, res)
+		if err != nil {
+			return nil, err
+		}
+
+		return build(d1, d2), nil
+	}, typeOf[D1](), typeOf[D2]()))...)
+}
+
+// AssertBuilder checks at compile time that build is a builder without
+// dependencies and returns it to be registered:
+//
+//	c.Register(Identity("db"), AssertBuilder(newDB))
+//
+// A function which isn't a builder fails to compile instead of failing
+// Register, e.g. one without a return, like func(), or returning more
+// than the instance, like func() (*DB, int). The shapes which aren't
+// covered by the helpers, e.g. a builder returning a cleanup, are still
+// registered directly.
+func AssertBuilder[T any](build func() T) Builder {
+	return build
+}
+
+// AssertBuilderE is AssertBuilder for a builder which returns an error
+func AssertBuilderE[T any](build func() (T, error)) Builder {
+	return build
+}
+
+// AssertBuilder1 is AssertBuilder for a builder taking one dependency
+func AssertBuilder1[T, D1 any](build func(D1) T) Builder {
+	return build
+}
+
+// AssertBuilder1E is AssertBuilder1 for a builder which returns an error
+func AssertBuilder1E[T, D1 any](build func(D1) (T, error)) Builder {
+	return build
+}
+
+// AssertBuilder2 is AssertBuilder for a builder taking two dependencies
+func AssertBuilder2[T, D1, D2 any](build func(D1, D2) T) Builder {
+	return build
+}
+
+// AssertBuilder2E is AssertBuilder2 for a builder which returns an error
+func AssertBuilder2E[T, D1, D2 any](build func(D1, D2) (T, error)) Builder {
+	return build
+}
@@ -0,0 +1,135 @@
+package objectcommander
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testRequest struct {
+	name string
+	port int
+}
+
+func TestRegisterFunc(t *testing.T) {
+
+	c := NewContainer()
+	RegisterFunc0(c, Identity("name"), func() string { return "api" })
+	RegisterFunc1(c, Identity("port"), func(name string) int { return len(name) })
+	RegisterFunc2(c, Identity("request"), func(name string, port int) *testRequest {
+		return &testRequest{name: name, port: port}
+	})
+
+	request := c.MustGet(Identity("request")).(*testRequest)
+	if request.name != "api" || request.port != 3 {
+		t.Errorf("unexpected instance: %+v", request)
+	}
+
+	if c.TypeOf(Identity("request")) != typeOf[*testRequest]() {
+		t.Error("the typed builder should be indexed by its type")
+	}
+
+	created, err := c.Create(Identity("request"))
+	if err != nil || created == request {
+		t.Errorf("Create should build a new instance: %v", err)
+	}
+
+	RegisterFunc1(c, Identity("broken"), func(float64) bool { return true })
+	if _, err := c.Get(Identity("broken")); err == nil {
+		t.Error("should fail for a dependency which isn't registered")
+	}
+
+	// the reflection path is used when a hook needs the dependencies
+	traced := []Identity{}
+	c.BeforeBuild(func(name Identity, deps []Identity) { traced = append(traced, deps...) })
+	c.Create(Identity("request"))
+	if len(traced) != 2 {
+		t.Errorf("the hook should see the dependencies: %v", traced)
+	}
+}
+
+func TestRegisterFuncNilConverted(t *testing.T) {
+
+	c := NewContainer()
+	RegisterFunc0(c, Identity("name"), func() string { return "api" })
+	c.RegisterConverter(reflect.TypeOf(""), reflect.TypeOf((*testReader)(nil)).Elem(), func(v interface{}) interface{} {
+		return nil
+	})
+	RegisterFunc1(c, Identity("reader"), func(r testReader) bool { return r == nil })
+
+	if nilReader, err := c.Get(Identity("reader")); err != nil || nilReader != true {
+		t.Errorf("a nil converted interface should be the zero value: %v %v", nilReader, err)
+	}
+}
+
+func newBenchmarkContainer() *Container {
+	c := NewContainer()
+	c.Register(Identity("name"), func() string { return "api" })
+	c.Register(Identity("port"), func() int { return 8080 })
+	c.MustGet(Identity("name"))
+	c.MustGet(Identity("port"))
+
+	return c
+}
+
+func TestAssertBuilder(t *testing.T) {
+
+	type Config struct{ DSN string }
+	type DB struct{ Config *Config }
+	type Repo struct {
+		DB   *DB
+		Name string
+	}
+
+	// the functions below fail to compile with a helper:
+	//	AssertBuilder(func() {})                   // nothing is built
+	//	AssertBuilderE(func() (*DB, int) { ... })  // the second return isn't an error
+	//	AssertBuilder1(func(a, b *DB) *Repo { ... }) // the number of dependencies differs
+	c := NewContainer()
+	for name, build := range map[Identity]Builder{
+		"config": AssertBuilder(func() *Config { return &Config{DSN: "postgres://"} }),
+		"db":     AssertBuilder1E(func(config *Config) (*DB, error) { return &DB{Config: config}, nil }),
+		"name":   AssertBuilderE(func() (string, error) { return "users", nil }),
+		"repo":   AssertBuilder2(func(db *DB, name string) *Repo { return &Repo{DB: db, Name: name} }),
+		"port":   AssertBuilder1(func(config *Config) int { return 5432 }),
+		"ready":  AssertBuilder2E(func(db *DB, port int) (bool, error) { return true, nil }),
+	} {
+		if err := c.Register(name, build); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := c.MustGet(Identity("repo")).(*Repo)
+	if repo.Name != "users" || repo.DB.Config.DSN != "postgres://" {
+		t.Errorf("unexpected repo: %+v", repo)
+	}
+
+	if !c.MustGet(Identity("ready")).(bool) {
+		t.Error("the builder with two dependencies and an error should be registered")
+	}
+}
+
+func BenchmarkCreateTyped(b *testing.B) {
+
+	c := newBenchmarkContainer()
+	RegisterFunc2(c, Identity("request"), func(name string, port int) *testRequest {
+		return &testRequest{name: name, port: port}
+	})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Create(Identity("request"))
+	}
+}
+
+func BenchmarkCreateReflection(b *testing.B) {
+
+	c := newBenchmarkContainer()
+	c.Register(Identity("request"), func(name string, port int) *testRequest {
+		return &testRequest{name: name, port: port}
+	})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Create(Identity("request"))
+	}
+}
@@ -0,0 +1,121 @@
+package objectcommander
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Validate checks the wiring of every definition without building
+// anything. It returns an error listing each parameter which can't be
+// resolved and each dependency cycle, so a broken wiring is found by a
+// test instead of at the first Get. A Lazy[T] parameter doesn't form a
+// cycle since it's resolved on demand. The definitions taking args from
+// GetWithArgs are skipped.
+func (c *Container) Validate() error {
+	c.RLock()
+	names := make([]Identity, 0, len(c.defs))
+	for name, def := range c.defs {
+		if !def.withArgs {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return c.defs[names[i]].seq < c.defs[names[j]].seq })
+
+	deps := make(map[Identity][]dependency, len(names))
+	for _, name := range names {
+		deps[name] = c.defs[name].dependencies()
+	}
+	c.RUnlock()
+
+	errs := []error{}
+	edges := make(map[Identity][]Identity, len(names))
+	for _, name := range names {
+		for _, dep := range deps[name] {
+			id, exists, err := c.lookup(dep.t)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s depends on %s: %w", name, dep.t, err))
+				continue
+			}
+
+			if !exists {
+				if _, derivable, _ := c.lookup(derivedFrom(dep.t)); !derivable && !c.convertible(dep.t) {
+					errs = append(errs, fmt.Errorf("%s depends on %s which is not registered", name, dep.t))
+				}
+				continue
+			}
+
+			if !dep.lazy {
+				edges[name] = append(edges[name], id)
+			}
+		}
+	}
+
+	return combineErrors(append(errs, cycles(names, edges)...))
+}
+
+// convertible tells whether a converter can resolve the type
+func (c *Container) convertible(to reflect.Type) bool {
+	for cur := c; cur != nil; cur = cur.Parent() {
+		cur.RLock()
+		converters := cur.converters[to]
+		cur.RUnlock()
+
+		for _, conv := range converters {
+			if _, exists, _ := c.lookup(conv.from); exists {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// cycles returns an error for every cycle of the dependency graph
+func cycles(names []Identity, edges map[Identity][]Identity) []error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	errs := []error{}
+	state := make(map[Identity]int, len(names))
+	path := []Identity{}
+
+	var visit func(name Identity)
+	visit = func(name Identity) {
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, dep := range edges[name] {
+			switch state[dep] {
+			case visiting:
+				ids := []string{}
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i] == dep {
+						for _, id := range path[i:] {
+							ids = append(ids, string(id))
+						}
+						break
+					}
+				}
+				ids = append(ids, string(dep))
+				errs = append(errs, fmt.Errorf("dependency cycle detected: %s", strings.Join(ids, " -> ")))
+			case 0:
+				visit(dep)
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+	}
+
+	for _, name := range names {
+		if state[name] == 0 {
+			visit(name)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,48 @@
+package objectcommander
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+
+	type Config struct{ DSN string }
+	type DB struct{ Config *Config }
+	type Ping struct{ Pong interface{} }
+	type Pong struct{ Ping *Ping }
+	type Left struct{ Right interface{} }
+	type Right struct{ Left *Left }
+
+	c := NewContainer()
+	c.Register(Identity("config"), func() *Config { return &Config{} })
+	c.Register(Identity("db"), func(config *Config) *DB { return &DB{Config: config} })
+
+	if err := c.Validate(); err != nil {
+		t.Fatalf("the wiring should be valid: %v", err)
+	}
+
+	c.Register(Identity("repo"), func(db *DB, port int) string { return "repo" })
+	c.Register(Identity("ping"), func(pong *Pong) *Ping { return &Ping{Pong: pong} })
+	c.Register(Identity("pong"), func(ping *Ping) *Pong { return &Pong{Ping: ping} })
+	c.Register(Identity("left"), func(right Lazy[*Right]) *Left { return &Left{Right: right} })
+	c.Register(Identity("right"), func(left *Left) *Right { return &Right{Left: left} })
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("the wiring should be invalid")
+	}
+
+	for _, expected := range []string{
+		"repo depends on int which is not registered",
+		"dependency cycle detected: ping -> pong -> ping",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q should be reported: %v", expected, err)
+		}
+	}
+
+	if strings.Count(err.Error(), ";") != 1 {
+		t.Errorf("only two problems should be reported: %v", err)
+	}
+}
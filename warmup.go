@@ -0,0 +1,12 @@
+package objectcommander
+
+// RegisterWithWarmup is Register with a warmup which runs once the
+// instance is built and before it's stored, e.g. to pre-populate the
+// downstream caches. The build fails if the warmup fails, so nothing is
+// stored and the next Get builds the instance and runs the warmup again.
+// The instances built by Create aren't warmed up.
+func (c *Container) RegisterWithWarmup(name Identity, build Builder, warmup func(v interface{}) error, opts ...RegisterOption) error {
+	return c.Register(name, build, append(opts, func(d *definition) {
+		d.warmup = warmup
+	})...)
+}
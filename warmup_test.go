@@ -0,0 +1,45 @@
+package objectcommander
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegisterWithWarmup(t *testing.T) {
+
+	type CDN struct{ Entries map[string]string }
+
+	warmups := 0
+	failing := true
+	c := NewContainer()
+	c.RegisterWithWarmup(Identity("cdn"), func() *CDN {
+		return &CDN{Entries: map[string]string{}}
+	}, func(v interface{}) error {
+		warmups++
+		if failing {
+			return errors.New("origin unreachable")
+		}
+		v.(*CDN).Entries["/"] = "index"
+		return nil
+	})
+
+	if _, err := c.Get(Identity("cdn")); err == nil {
+		t.Fatal("a failed warmup should fail the build")
+	}
+
+	if _, built := c.Peek(Identity("cdn")); built {
+		t.Error("nothing should be stored when the warmup fails")
+	}
+
+	failing = false
+	first := c.MustGet(Identity("cdn")).(*CDN)
+	second := c.MustGet(Identity("cdn")).(*CDN)
+
+	if first != second || first.Entries["/"] != "index" {
+		t.Errorf("the warmed up instance should be stored: %+v", first)
+	}
+
+	if warmups != 2 {
+		t.Errorf("the warmup should run once per build: %d", warmups)
+	}
+}
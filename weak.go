@@ -0,0 +1,50 @@
+package objectcommander
+
+// WeakSingleton makes the cached instance be dropped once every borrow of
+// it is released, so it can be garbage collected when nobody uses it and
+// is rebuilt by the next Get. See Borrow and Release.
+func WeakSingleton() RegisterOption {
+	return func(d *definition) {
+		d.weak = true
+	}
+}
+
+// Borrow gets the instance and holds a reference of it until Release is
+// called
+func (c *Container) Borrow(name Identity) (interface{}, error) {
+	result, err := c.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.borrows == nil {
+		c.borrows = make(map[Identity]int)
+	}
+	c.borrows[name]++
+
+	return result, nil
+}
+
+// Release returns a reference taken by Borrow. The instance of a weak
+// singleton is dropped from the container once there is no reference.
+func (c *Container) Release(name Identity) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.borrows[name] == 0 {
+		return
+	}
+
+	c.borrows[name]--
+	if c.borrows[name] > 0 {
+		return
+	}
+
+	delete(c.borrows, name)
+	if def, exists := c.defs[name]; exists && def.weak {
+		c.evict(name)
+	}
+}
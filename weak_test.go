@@ -0,0 +1,54 @@
+package objectcommander
+
+import (
+	"testing"
+)
+
+func TestWeakSingleton(t *testing.T) {
+
+	type Cache struct{ Version int }
+
+	built := 0
+	c := NewContainer()
+	c.Register(Identity("cache"), func() *Cache {
+		built++
+		return &Cache{Version: built}
+	}, WeakSingleton())
+
+	first, _ := c.Borrow(Identity("cache"))
+	second, _ := c.Borrow(Identity("cache"))
+	if first != second {
+		t.Error("borrows should share the instance")
+	}
+
+	c.Release(Identity("cache"))
+	if c.MustGet(Identity("cache")) != first {
+		t.Error("the instance should be kept while it's borrowed")
+	}
+
+	c.Release(Identity("cache"))
+	if c.MustGet(Identity("cache")).(*Cache).Version != 2 {
+		t.Error("the instance should be rebuilt after every borrow is released")
+	}
+
+	// releasing more than borrowed is a no-op
+	c.Release(Identity("cache"))
+}
+
+func TestBorrowStrongSingleton(t *testing.T) {
+
+	built := 0
+	c := NewContainer()
+	c.Register(Identity("db"), func() string {
+		built++
+		return "db"
+	})
+
+	c.Borrow(Identity("db"))
+	c.Release(Identity("db"))
+	c.MustGet(Identity("db"))
+
+	if built != 1 {
+		t.Error("a strong singleton should be kept after the release")
+	}
+}
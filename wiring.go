@@ -0,0 +1,159 @@
+package objectcommander
+
+import (
+	"reflect"
+	"sort"
+)
+
+// WireEdge is a dependency of a definition. To is empty when there is
+// nothing registered to satisfy the parameter.
+type WireEdge struct {
+	From      Identity
+	ParamType reflect.Type
+	To        Identity
+}
+
+// dependency is a type the definition depends on
+type dependency struct {
+	t    reflect.Type
+	lazy bool // lazy is true for a Lazy[T] parameter which is resolved on demand
+}
+
+// dependencyTypes returns the types the definition depends on. A Lazy[T]
+// parameter depends on T and the injected once guard is skipped.
+func (d *definition) dependencyTypes() []reflect.Type {
+	deps := d.dependencies()
+	types := make([]reflect.Type, 0, len(deps))
+	for _, dep := range deps {
+		types = append(types, dep.t)
+	}
+
+	return types
+}
+
+// dependencies is dependencyTypes telling the Lazy[T] parameters apart
+func (d *definition) dependencies() []dependency {
+	if d.build == nil {
+		return nil
+	}
+
+	ftype := reflect.TypeOf(d.build)
+	numArgs := ftype.NumIn()
+	if ftype.IsVariadic() {
+		numArgs--
+	}
+
+	deps := make([]dependency, 0, numArgs)
+	for i := 0; i < numArgs; i++ {
+		argType := ftype.In(i)
+
+		if d.idempotentStart && argType == onceType {
+			continue
+		}
+
+		if elem, isLazy := lazyElem(argType); isLazy {
+			deps = append(deps, dependency{t: elem, lazy: true})
+			continue
+		}
+
+		if isDeps(argType) {
+			for _, t := range depsFieldTypes(argType) {
+				deps = append(deps, dependency{t: t})
+			}
+			continue
+		}
+
+		deps = append(deps, dependency{t: argType})
+	}
+
+	return deps
+}
+
+// WiringReport returns every dependency edge of the registered definitions
+// ordered by the registration. It's the machine-readable wiring which can
+// be checked by a generator or a test before anything is built.
+func (c *Container) WiringReport() []WireEdge {
+	c.RLock()
+	names := make([]Identity, 0, len(c.defs))
+	for name := range c.defs {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return c.defs[names[i]].seq < c.defs[names[j]].seq })
+
+	types := make(map[Identity][]reflect.Type, len(names))
+	for _, name := range names {
+		types[name] = c.defs[name].dependencyTypes()
+	}
+	c.RUnlock()
+
+	edges := []WireEdge{}
+	for _, name := range names {
+		for _, t := range types[name] {
+			to, _, _ := c.lookup(t)
+			edges = append(edges, WireEdge{From: name, ParamType: t, To: to})
+		}
+	}
+
+	return edges
+}
+
+// Dependencies returns the identities the definition depends on. The
+// dependencies which can't be resolved are left out.
+func (c *Container) Dependencies(name Identity) []Identity {
+	c.RLock()
+	def, exists := c.defs[name]
+	var types []reflect.Type
+	if exists {
+		types = def.dependencyTypes()
+	}
+	c.RUnlock()
+
+	if !exists {
+		return nil
+	}
+
+	ids := []Identity{}
+	for _, t := range types {
+		if id, exists, err := c.lookup(t); exists && err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// Dependents returns the identities whose builders take a parameter of
+// type t, ordered by the registration. It's the reverse of Dependencies
+// and tells what is affected by replacing t.
+func (c *Container) Dependents(t reflect.Type) []Identity {
+	c.RLock()
+	defer c.RUnlock()
+
+	ids := []Identity{}
+	for name, def := range c.defs {
+		for _, dep := range def.dependencyTypes() {
+			if dep == t {
+				ids = append(ids, name)
+				break
+			}
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return c.defs[ids[i]].seq < c.defs[ids[j]].seq })
+
+	return ids
+}
+
+// TypeOf returns the type of the instance built by the definition. It's
+// nil if the identity isn't registered or it's a lazy definition which
+// isn't provided yet.
+func (c *Container) TypeOf(name Identity) reflect.Type {
+	c.RLock()
+	defer c.RUnlock()
+
+	def, exists := c.defs[name]
+	if !exists {
+		return nil
+	}
+
+	return def.outType()
+}
@@ -0,0 +1,58 @@
+package objectcommander
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWiringReport(t *testing.T) {
+
+	type Config struct{}
+	type DB struct{}
+	type Mailer struct{}
+	type Service struct{}
+
+	c := NewContainer()
+	c.Register(Identity("config"), func() Config { return Config{} })
+	c.Register(Identity("db"), func(Config) DB { return DB{} })
+	c.Register(Identity("service"), func(DB, Lazy[Config], Mailer) Service { return Service{} })
+
+	expected := []WireEdge{
+		{From: Identity("db"), ParamType: reflect.TypeOf(Config{}), To: Identity("config")},
+		{From: Identity("service"), ParamType: reflect.TypeOf(DB{}), To: Identity("db")},
+		{From: Identity("service"), ParamType: reflect.TypeOf(Config{}), To: Identity("config")},
+		{From: Identity("service"), ParamType: reflect.TypeOf(Mailer{}), To: Identity("")},
+	}
+
+	if report := c.WiringReport(); !reflect.DeepEqual(report, expected) {
+		t.Errorf("unexpected report: %v", report)
+	}
+
+	if deps := c.Dependencies(Identity("service")); !reflect.DeepEqual(deps, []Identity{"db", "config"}) {
+		t.Errorf("unexpected dependencies: %v", deps)
+	}
+
+	if c.TypeOf(Identity("db")) != reflect.TypeOf(DB{}) || c.TypeOf(Identity("nop")) != nil {
+		t.Error("unexpected type of the definition")
+	}
+}
+
+func TestDependents(t *testing.T) {
+
+	type Config struct{}
+	type DB struct{}
+	type Cache struct{}
+
+	c := NewContainer()
+	c.Register(Identity("config"), func() Config { return Config{} })
+	c.Register(Identity("db"), func(Config) DB { return DB{} })
+	c.Register(Identity("cache"), func(Lazy[Config]) Cache { return Cache{} })
+
+	if ids := c.Dependents(reflect.TypeOf(Config{})); !reflect.DeepEqual(ids, []Identity{"db", "cache"}) {
+		t.Errorf("unexpected dependents: %v", ids)
+	}
+
+	if ids := c.Dependents(reflect.TypeOf(Cache{})); len(ids) != 0 {
+		t.Errorf("nothing should depend on the cache: %v", ids)
+	}
+}